@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/pkg/errors"
+)
+
+const accessDeniedContact = "Please talk to your system administrator to get access."
+
+// checkAccessPolicy returns a message explaining why the configured access
+// policy denies the user, or an empty string if the user is allowed. Each
+// configured rule must be met: the user must not be excluded, must have an
+// email in an allowed domain, must have an allowed role and must be a member
+// of an allowed team.
+func checkAccessPolicy(config *configuration, user *model.User, teamNames []string) string {
+	for _, username := range config.getExcludedUsers() {
+		if strings.ToLower(user.Username) == username {
+			return "Permission denied. Your account has been excluded from using the Steam plugin. " + accessDeniedContact
+		}
+	}
+
+	if domains := config.getAllowedEmailDomains(); len(domains) > 0 {
+		var emailDomain string
+		if at := strings.LastIndex(user.Email, "@"); at >= 0 {
+			emailDomain = strings.ToLower(user.Email[at+1:])
+		}
+		if !containsString(domains, emailDomain) {
+			return fmt.Sprintf("Permission denied. The Steam plugin is only available to users with an email address at %s. %s", strings.Join(domains, ", "), accessDeniedContact)
+		}
+	}
+
+	if roles := config.getAllowedRoles(); len(roles) > 0 {
+		var allowed bool
+		for _, role := range strings.Fields(strings.ToLower(user.Roles)) {
+			if containsString(roles, role) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Sprintf("Permission denied. The Steam plugin is only available to users with one of these roles: %s. %s", strings.Join(roles, ", "), accessDeniedContact)
+		}
+	}
+
+	if teams := config.getAllowedTeams(); len(teams) > 0 {
+		var allowed bool
+		for _, teamName := range teamNames {
+			if containsString(teams, strings.ToLower(teamName)) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Sprintf("Permission denied. The Steam plugin is only available to members of these teams: %s. %s", strings.Join(teams, ", "), accessDeniedContact)
+		}
+	}
+
+	return ""
+}
+
+// checkUserAccess returns a message explaining why the user is denied access
+// to the plugin, or an empty string if they are allowed.
+func (p *Plugin) checkUserAccess(userID string) (string, error) {
+	config := p.getConfiguration()
+
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		return "", errors.Wrap(appErr, "unable to get user")
+	}
+
+	var teamNames []string
+	if len(config.getAllowedTeams()) > 0 {
+		teams, appErr := p.API.GetTeamsForUser(userID)
+		if appErr != nil {
+			return "", errors.Wrap(appErr, "unable to get teams for user")
+		}
+		for _, team := range teams {
+			teamNames = append(teamNames, team.Name)
+		}
+	}
+
+	return checkAccessPolicy(config, user, teamNames), nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
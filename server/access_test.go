@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckAccessPolicy(t *testing.T) {
+	user := &model.User{Username: "gamer", Email: "gamer@mattermost.com", Roles: "system_user"}
+
+	t.Run("no policy", func(t *testing.T) {
+		assert.Empty(t, checkAccessPolicy(&configuration{}, user, nil))
+	})
+
+	t.Run("email domains", func(t *testing.T) {
+		config := &configuration{AllowedEmailDomain: "example.org, mattermost.com"}
+		assert.Empty(t, checkAccessPolicy(config, user, nil))
+
+		suffixUser := &model.User{Username: "evil", Email: "evil@evil-mattermost.com"}
+		assert.NotEmpty(t, checkAccessPolicy(config, suffixUser, nil))
+	})
+
+	t.Run("roles", func(t *testing.T) {
+		config := &configuration{AllowedRoles: "system_admin"}
+		assert.NotEmpty(t, checkAccessPolicy(config, user, nil))
+
+		config.AllowedRoles = "system_admin, system_user"
+		assert.Empty(t, checkAccessPolicy(config, user, nil))
+	})
+
+	t.Run("teams", func(t *testing.T) {
+		config := &configuration{AllowedTeams: "gaming"}
+		assert.NotEmpty(t, checkAccessPolicy(config, user, []string{"engineering"}))
+		assert.Empty(t, checkAccessPolicy(config, user, []string{"engineering", "Gaming"}))
+	})
+
+	t.Run("excluded users", func(t *testing.T) {
+		config := &configuration{ExcludedUsers: "@someone, Gamer"}
+		assert.Contains(t, checkAccessPolicy(config, user, nil), "excluded")
+	})
+}
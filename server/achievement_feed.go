@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// AchievementStateKey is the store prefix for the achievements a user has
+	// unlocked in a game. Achievement prefixes are kept short so that keys fit
+	// within the store's key length limit.
+	AchievementStateKey = "ach_state_"
+
+	// AchievementFeedCountKey is the store prefix for the number of
+	// achievements posted for a user on a given day.
+	AchievementFeedCountKey = "ach_count_"
+
+	// achievementFeedInterval is how often achievements are checked.
+	achievementFeedInterval = 15 * time.Minute
+
+	// achievementRareThreshold is the global unlock percentage below which an
+	// achievement is highlighted as rare.
+	achievementRareThreshold = 10.0
+)
+
+func achievementStateKey(userID string, appID int64) string {
+	return fmt.Sprintf("%s%s_%d", AchievementStateKey, userID, appID)
+}
+
+func achievementFeedCountKey(userID string, date time.Time) string {
+	return fmt.Sprintf("%s%s_%s", AchievementFeedCountKey, userID, date.UTC().Format(playtimeSnapshotKeyDateFormat))
+}
+
+// getAchievementState returns the API names of the achievements a user was
+// last seen to have unlocked in a game. False is returned if the game hasn't
+// been seen before.
+func (p *Plugin) getAchievementState(userID string, appID int64) (map[string]bool, bool, error) {
+	stateBytes, appErr := p.API.KVGet(achievementStateKey(userID, appID))
+	if appErr != nil {
+		return nil, false, errors.Wrap(appErr, "unable to get achievement state")
+	}
+	if stateBytes == nil {
+		return nil, false, nil
+	}
+
+	var unlocked []string
+	err := json.Unmarshal(stateBytes, &unlocked)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "unable to parse achievement state")
+	}
+
+	state := make(map[string]bool)
+	for _, name := range unlocked {
+		state[name] = true
+	}
+
+	return state, true, nil
+}
+
+func (p *Plugin) storeAchievementState(userID string, appID int64, stats *PlayerStats) error {
+	unlocked := []string{}
+	for _, achievement := range stats.Achievements {
+		if achievement.Achieved == 1 {
+			unlocked = append(unlocked, achievement.APIName)
+		}
+	}
+
+	stateBytes, err := json.Marshal(unlocked)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal achievement state")
+	}
+
+	appErr := p.API.KVSet(achievementStateKey(userID, appID), stateBytes)
+	if appErr != nil {
+		return errors.Wrap(appErr, "unable to store achievement state in database")
+	}
+
+	return nil
+}
+
+// getAchievementFeedCount returns the number of achievements posted for a
+// user today.
+func (p *Plugin) getAchievementFeedCount(userID string, now time.Time) (int, error) {
+	countBytes, appErr := p.API.KVGet(achievementFeedCountKey(userID, now))
+	if appErr != nil {
+		return 0, errors.Wrap(appErr, "unable to get achievement feed count")
+	}
+	if countBytes == nil {
+		return 0, nil
+	}
+
+	return strconv.Atoi(string(countBytes))
+}
+
+func (p *Plugin) storeAchievementFeedCount(userID string, now time.Time, count int) error {
+	appErr := p.API.KVSetWithExpiry(achievementFeedCountKey(userID, now), []byte(strconv.Itoa(count)), int64((48 * time.Hour).Seconds()))
+	if appErr != nil {
+		return errors.Wrap(appErr, "unable to store achievement feed count in database")
+	}
+
+	return nil
+}
+
+// newlyUnlockedAchievements returns the achievements in progress that are
+// unlocked but weren't in the previous state, filtered to those at or below
+// the rarity threshold.
+func newlyUnlockedAchievements(progress *AchievementProgress, previous map[string]bool, threshold float64) []Achievement {
+	var unlocked []Achievement
+	for _, achievement := range progress.Achievements {
+		if !achievement.Achieved || previous[achievement.APIName] {
+			continue
+		}
+		if achievement.GlobalPercent > threshold {
+			continue
+		}
+		unlocked = append(unlocked, achievement)
+	}
+
+	return unlocked
+}
+
+func achievementFeedJobInterval(config *configuration) time.Duration {
+	if !config.AchievementFeedEnable {
+		return 0
+	}
+
+	return achievementFeedInterval
+}
+
+// runAchievementFeedJob posts new achievements unlocked in recently played
+// games by users who opted in to the achievement feed.
+func (p *Plugin) runAchievementFeedJob() error {
+	config := p.getConfiguration()
+	threshold, err := config.getAchievementRarityThreshold()
+	if err != nil {
+		return err
+	}
+	dailyCap, err := config.getAchievementDailyCap()
+	if err != nil {
+		return err
+	}
+
+	userIDs, err := p.getSteamUserIDsForAccess(accessAchievements)
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		err = p.postNewAchievementsForUser(userID, config.AchievementFeedChannelID, threshold, dailyCap)
+		if err != nil {
+			p.API.LogError(errors.Wrapf(err, "unable to post achievements for %s", userID).Error())
+		}
+	}
+
+	return nil
+}
+
+func (p *Plugin) postNewAchievementsForUser(userID, channelID string, threshold float64, dailyCap int) error {
+	games, err := p.getRecentlyPlayedGames(userID, accessAchievements)
+	if err != nil {
+		return err
+	}
+
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		return errors.Wrapf(appErr, "unable to get user %s", userID)
+	}
+
+	now := time.Now()
+	count, err := p.getAchievementFeedCount(userID, now)
+	if err != nil {
+		return err
+	}
+
+	for _, game := range games {
+		// Games are skipped without storing state when Steam doesn't return
+		// achievements, so that a failed response isn't seen as every
+		// achievement being locked.
+		stats, err := p.getPlayerAchievements(userID, game.AppID, accessAchievements)
+		if err == errNoAchievements {
+			continue
+		}
+		if err != nil {
+			p.API.LogError(errors.Wrapf(err, "unable to get achievements for app %d", game.AppID).Error())
+			continue
+		}
+
+		previous, seen, err := p.getAchievementState(userID, game.AppID)
+		if err != nil {
+			return err
+		}
+
+		// The first time a game is seen only records the current state so
+		// that existing achievements aren't posted.
+		if seen && count < dailyCap && hasNewAchievements(stats, previous) {
+			progress, err := p.getAchievementProgress(userID, game.AppID, accessAchievements)
+			if err != nil {
+				p.API.LogError(errors.Wrapf(err, "unable to get achievement details for app %d", game.AppID).Error())
+				continue
+			}
+
+			for _, achievement := range newlyUnlockedAchievements(progress, previous, threshold) {
+				if count >= dailyCap {
+					break
+				}
+
+				err = p.PostToChannelByIDAsBot(channelID, formatAchievementUnlock(user.Username, &game, &achievement))
+				if err != nil {
+					return err
+				}
+				count++
+			}
+
+			err = p.storeAchievementFeedCount(userID, now, count)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = p.storeAchievementState(userID, game.AppID, stats)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func hasNewAchievements(stats *PlayerStats, previous map[string]bool) bool {
+	for _, achievement := range stats.Achievements {
+		if achievement.Achieved == 1 && !previous[achievement.APIName] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func formatAchievementUnlock(username string, game *Game, achievement *Achievement) string {
+	message := fmt.Sprintf("![%s](%s =32x32) @%s unlocked **%s** in [%s](%s)",
+		achievement.Name, achievement.Icon, username, achievement.Name, game.Name, game.StoreLink())
+	if achievement.Description != "" {
+		message += fmt.Sprintf(": %s", achievement.Description)
+	}
+	message += fmt.Sprintf(" [%.1f%% of players]", achievement.GlobalPercent)
+	if achievement.GlobalPercent < achievementRareThreshold {
+		message += " :gem:"
+	}
+
+	return message
+}
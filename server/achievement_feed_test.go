@@ -0,0 +1,59 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHasNewAchievements(t *testing.T) {
+	stats := &PlayerStats{
+		Success: true,
+		Achievements: []PlayerAchievement{
+			{APIName: "first", Achieved: 1},
+			{APIName: "second", Achieved: 0},
+		},
+	}
+
+	assert.True(t, hasNewAchievements(stats, nil))
+	assert.True(t, hasNewAchievements(stats, map[string]bool{"second": true}))
+	assert.False(t, hasNewAchievements(stats, map[string]bool{"first": true}))
+	assert.False(t, hasNewAchievements(&PlayerStats{Success: true}, nil))
+}
+
+func TestNewlyUnlockedAchievements(t *testing.T) {
+	progress := &AchievementProgress{
+		Achievements: []Achievement{
+			{APIName: "seen", Achieved: true, GlobalPercent: 1},
+			{APIName: "rare", Achieved: true, GlobalPercent: 5},
+			{APIName: "common", Achieved: true, GlobalPercent: 60},
+			{APIName: "threshold", Achieved: true, GlobalPercent: 20},
+			{APIName: "locked", Achieved: false, GlobalPercent: 1},
+		},
+	}
+	previous := map[string]bool{"seen": true}
+
+	var names []string
+	for _, achievement := range newlyUnlockedAchievements(progress, previous, 20) {
+		names = append(names, achievement.APIName)
+	}
+	assert.Equal(t, []string{"rare", "threshold"}, names)
+
+	assert.Len(t, newlyUnlockedAchievements(progress, previous, 100), 3)
+	assert.Empty(t, newlyUnlockedAchievements(progress, previous, 0))
+}
+
+func TestAchievementKeyLength(t *testing.T) {
+	userID := model.NewId()
+
+	for _, key := range []string{
+		achievementStateKey(userID, math.MaxUint32),
+		achievementFeedCountKey(userID, time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)),
+	} {
+		assert.True(t, utf8.RuneCountInString(key) <= model.KEY_VALUE_KEY_MAX_RUNES, "%s is longer than %d runes", key, model.KEY_VALUE_KEY_MAX_RUNES)
+	}
+}
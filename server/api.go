@@ -2,11 +2,14 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/plugin"
 	"github.com/pkg/errors"
 )
@@ -23,10 +26,13 @@ type PlayersList struct {
 
 // Player is Steam player information.
 type Player struct {
-	SteamID     string `json:"steamid"`
-	PersonaName string `json:"personaname"`
-	ProfileURL  string `json:"profileurl"`
-	Avatar      string `json:"avatar"`
+	SteamID       string `json:"steamid"`
+	PersonaName   string `json:"personaname"`
+	ProfileURL    string `json:"profileurl"`
+	Avatar        string `json:"avatar"`
+	PersonaState  int    `json:"personastate"`
+	GameID        string `json:"gameid"`
+	GameExtraInfo string `json:"gameextrainfo"`
 }
 
 // SteamUserInfoRequest is the request type to obtain steam info for a given user.
@@ -34,6 +40,18 @@ type SteamUserInfoRequest struct {
 	UserID string `json:"user_id"`
 }
 
+// SteamUserInfoBatchRequest is the request type to obtain steam info for many
+// users.
+type SteamUserInfoBatchRequest struct {
+	UserIDs []string `json:"user_ids"`
+}
+
+// SteamUserInfoBatchResponse is the response type for a batch of steam info,
+// keyed by user ID.
+type SteamUserInfoBatchResponse struct {
+	Users map[string]*SteamUserInfoBatchResult `json:"users"`
+}
+
 // ServeHTTP handles HTTP requests to the plugin.
 func (p *Plugin) ServeHTTP(c *plugin.Context, w http.ResponseWriter, r *http.Request) {
 	config := p.getConfiguration()
@@ -45,11 +63,38 @@ func (p *Plugin) ServeHTTP(c *plugin.Context, w http.ResponseWriter, r *http.Req
 
 	w.Header().Set("Content-Type", "application/json")
 
+	// The profile image is public, every other route is subject to the
+	// access policy.
+	if userID := r.Header.Get("Mattermost-User-ID"); userID != "" && r.URL.Path != "/profile.png" {
+		denied, err := p.checkUserAccess(userID)
+		if err != nil {
+			p.API.LogError(err.Error())
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if denied != "" {
+			http.Error(w, denied, http.StatusForbidden)
+			return
+		}
+	}
+
 	switch path := r.URL.Path; path {
 	case "/profile.png":
 		p.handleProfileImage(w, r)
 	case "/api/v1/userinfo":
 		p.handleUserInfo(w, r)
+	case "/api/v1/userinfo/batch":
+		p.handleUserInfoBatch(w, r)
+	case eventRSVPPath:
+		p.handleEventRSVP(w, r)
+	case lfgActionPath:
+		p.handleLFGAction(w, r)
+	case settingsDialogPath:
+		p.handleSettingsDialog(w, r)
+	case connectDialogPath:
+		p.handleConnectDialog(w, r)
+	case auditExportPath:
+		p.handleAuditExport(w, r)
 	default:
 		http.NotFound(w, r)
 	}
@@ -79,17 +124,18 @@ func (p *Plugin) handleUserInfo(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	if !userInfo.Settings.ShowProfile {
+	if !userInfo.Settings.Allows(accessProfile) {
 		w.WriteHeader(http.StatusOK)
 		return
 	}
 
-	steamUserInfo, err := p.getSteamInfoForUser(userInfoRequest.UserID)
+	steamUserInfo, err := p.getSteamUserInfoResponse(userInfoRequest.UserID)
 	if err != nil {
 		p.API.LogError(err.Error())
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	steamUserInfo.ApplySettings(userInfo.Settings)
 
 	data, err := json.Marshal(steamUserInfo)
 	if err != nil {
@@ -100,6 +146,266 @@ func (p *Plugin) handleUserInfo(w http.ResponseWriter, r *http.Request) {
 	w.Write(data)
 }
 
+func (p *Plugin) handleUserInfoBatch(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	var batchRequest SteamUserInfoBatchRequest
+	err := json.NewDecoder(r.Body).Decode(&batchRequest)
+	if err != nil || len(batchRequest.UserIDs) == 0 {
+		if err != nil {
+			p.API.LogError(errors.Wrap(err, "Unable to decode steam user batch request").Error())
+		}
+
+		http.Error(w, "Please provide a JSON object with a non-empty user_ids field", http.StatusBadRequest)
+		return
+	}
+	if len(batchRequest.UserIDs) > userInfoBatchMaxUsers {
+		http.Error(w, fmt.Sprintf("A maximum of %d user_ids can be requested at once", userInfoBatchMaxUsers), http.StatusBadRequest)
+		return
+	}
+
+	data, err := json.Marshal(&SteamUserInfoBatchResponse{
+		Users: p.getSteamUserInfoBatch(batchRequest.UserIDs),
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(data)
+}
+
+func (p *Plugin) handleEventRSVP(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	request := model.PostActionIntegrationRequestFromJson(r.Body)
+	if request == nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	eventID, _ := request.Context["event_id"].(string)
+	response, _ := request.Context["response"].(string)
+	if eventID == "" || (response != RSVPGoing && response != RSVPMaybe && response != RSVPNotGoing) {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	event, err := p.setGameEventRSVP(eventID, userID, response)
+	if err == errNotChannelMember {
+		w.Write((&model.PostActionIntegrationResponse{
+			EphemeralText: "You must be a member of this channel to RSVP.",
+		}).ToJson())
+		return
+	}
+	if err != nil {
+		p.API.LogError(errors.Wrap(err, "Unable to store event RSVP").Error())
+		w.Write((&model.PostActionIntegrationResponse{
+			EphemeralText: "Unable to save your RSVP. This game night may have already ended.",
+		}).ToJson())
+		return
+	}
+
+	w.Write((&model.PostActionIntegrationResponse{
+		Update: p.makeGameEventPost(event),
+	}).ToJson())
+}
+
+func (p *Plugin) handleLFGAction(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	request := model.PostActionIntegrationRequestFromJson(r.Body)
+	if request == nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	lfgID, _ := request.Context["lfg_id"].(string)
+	action, _ := request.Context["action"].(string)
+	if lfgID == "" || (action != LFGActionJoin && action != LFGActionLeave) {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	lfg, message, err := p.updateLFG(lfgID, userID, action)
+	if err != nil {
+		p.API.LogError(errors.Wrap(err, "Unable to update group").Error())
+		w.Write((&model.PostActionIntegrationResponse{
+			EphemeralText: "Unable to update this group. It may have already expired.",
+		}).ToJson())
+		return
+	}
+	if message != "" {
+		w.Write((&model.PostActionIntegrationResponse{
+			EphemeralText: message,
+		}).ToJson())
+		return
+	}
+
+	w.Write((&model.PostActionIntegrationResponse{
+		Update: p.makeLFGPost(lfg),
+	}).ToJson())
+}
+
+func (p *Plugin) handleSettingsDialog(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	request := model.SubmitDialogRequestFromJson(r.Body)
+	if request == nil || request.CallbackId != settingsDialogCallbackID {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if request.Cancelled {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	userInfo, err := p.getSteamUserInfoByID(userID)
+	if err != nil {
+		w.Write((&model.SubmitDialogResponse{
+			Error: "You must connect your Steam account first.",
+		}).ToJson())
+		return
+	}
+
+	changes, fieldErrors := applySettingsSubmission(userInfo.Settings, request.Submission)
+	if len(fieldErrors) > 0 {
+		w.Write((&model.SubmitDialogResponse{
+			Errors: fieldErrors,
+		}).ToJson())
+		return
+	}
+
+	err = p.storeSteamUser(userInfo)
+	if err != nil {
+		p.API.LogError(errors.Wrap(err, "Unable to store settings").Error())
+		w.Write((&model.SubmitDialogResponse{
+			Error: "Unable to save your settings.",
+		}).ToJson())
+		return
+	}
+	if len(changes) > 0 {
+		p.recordAudit(userID, userID, AuditActionSettingsChange, changes)
+	}
+
+	p.API.SendEphemeralPost(userID, &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: request.ChannelId,
+		Message:   "Your Steam settings were updated.",
+	})
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (p *Plugin) handleConnectDialog(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	request := model.SubmitDialogRequestFromJson(r.Body)
+	if request == nil || request.CallbackId != connectDialogCallbackID {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if request.Cancelled {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	steamID, _ := request.Submission["steam_id"].(string)
+	apiKey, _ := request.Submission["api_key"].(string)
+	steamID = strings.TrimSpace(steamID)
+	apiKey = strings.TrimSpace(apiKey)
+
+	fieldErrors := make(map[string]string)
+	if steamID == "" {
+		fieldErrors["steam_id"] = "A Steam ID is required."
+	} else if !isValidSteamID(steamID) {
+		fieldErrors["steam_id"] = "A Steam ID is the number shown in your Steam profile URL."
+	}
+	if apiKey == "" {
+		fieldErrors["api_key"] = "A Steam API key is required."
+	}
+	if len(fieldErrors) > 0 {
+		w.Write((&model.SubmitDialogResponse{
+			Errors: fieldErrors,
+		}).ToJson())
+		return
+	}
+
+	err := validateSteamCredentials(steamID, apiKey)
+	if err != nil {
+		w.Write((&model.SubmitDialogResponse{
+			Error: "Unable to verify your Steam ID and API key. Please check them and try again.",
+		}).ToJson())
+		return
+	}
+
+	err = p.connectSteamUser(userID, steamID, apiKey)
+	if err != nil {
+		p.API.LogError(errors.Wrap(err, "Unable to store steam user").Error())
+		w.Write((&model.SubmitDialogResponse{
+			Error: "Unable to connect your Steam account.",
+		}).ToJson())
+		return
+	}
+	p.recordAudit(userID, userID, AuditActionConnect, map[string]string{"steam_id": steamID})
+
+	p.API.SendEphemeralPost(userID, &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: request.ChannelId,
+		Message:   connectedMessage,
+	})
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (p *Plugin) handleAuditExport(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" || !p.API.HasPermissionTo(userID, model.PERMISSION_MANAGE_SYSTEM) {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	entries, err := p.getAuditEntries(r.URL.Query().Get("user_id"))
+	if err != nil {
+		p.API.LogError(err.Error())
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []*AuditEntry{}
+	}
+
+	response, err := json.Marshal(entries)
+	if err != nil {
+		p.API.LogError(err.Error())
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", "attachment; filename=steam-audit-log.json")
+	w.Write(response)
+}
+
 func (p *Plugin) handleProfileImage(w http.ResponseWriter, r *http.Request) {
 	bundlePath, err := p.API.GetBundlePath()
 	if err != nil {
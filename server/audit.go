@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// AuditLogKey is the store prefix for audit log entries. Each entry is
+	// stored under its own key so that the log is only ever appended to.
+	AuditLogKey = "audit_log_"
+
+	auditExportPath = "/api/v1/admin/audit"
+)
+
+// Audit log actions.
+const (
+	AuditActionConnect         = "connect"
+	AuditActionDisconnect      = "disconnect"
+	AuditActionSettingsChange  = "settings_change"
+	AuditActionAdminDisconnect = "admin_disconnect"
+	AuditActionAdminRefresh    = "admin_refresh"
+	AuditActionAdminPurgeCache = "admin_purge_cache"
+)
+
+// AuditEntry is a record of a change to a user's account or settings.
+type AuditEntry struct {
+	ID        string            `json:"id"`
+	Timestamp int64             `json:"timestamp"`
+	ActorID   string            `json:"actor_id"`
+	UserID    string            `json:"user_id,omitempty"`
+	Action    string            `json:"action"`
+	Details   map[string]string `json:"details,omitempty"`
+}
+
+// Involves returns true if the user made the change or was acted on.
+func (e *AuditEntry) Involves(userID string) bool {
+	return e.ActorID == userID || e.UserID == userID
+}
+
+// auditLogKey returns the key of an entry. Timestamps are zero-padded so
+// that keys sort in the order entries were recorded.
+func auditLogKey(entry *AuditEntry) string {
+	return fmt.Sprintf("%s%013d_%s", AuditLogKey, entry.Timestamp, entry.ID)
+}
+
+// recordAudit appends an entry to the audit log. Failures are logged rather
+// than returned so that auditing never blocks the change itself.
+func (p *Plugin) recordAudit(actorID, userID, action string, details map[string]string) {
+	entry := &AuditEntry{
+		ID:        model.NewId(),
+		Timestamp: model.GetMillis(),
+		ActorID:   actorID,
+		UserID:    userID,
+		Action:    action,
+		Details:   details,
+	}
+
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		p.API.LogError(errors.Wrap(err, "unable to marshal audit entry").Error())
+		return
+	}
+
+	appErr := p.API.KVSet(auditLogKey(entry), entryBytes)
+	if appErr != nil {
+		p.API.LogError(errors.Wrap(appErr, "unable to store audit entry in database").Error())
+	}
+}
+
+// getAuditEntries returns the audit log, oldest first. If userID is set, only
+// entries involving the user are returned.
+func (p *Plugin) getAuditEntries(userID string) ([]*AuditEntry, error) {
+	keys, err := p.getAllKVKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	var auditKeys []string
+	for _, key := range keys {
+		if strings.HasPrefix(key, AuditLogKey) {
+			auditKeys = append(auditKeys, key)
+		}
+	}
+	sort.Strings(auditKeys)
+
+	var entries []*AuditEntry
+	for _, key := range auditKeys {
+		entryBytes, appErr := p.API.KVGet(key)
+		if appErr != nil {
+			return nil, errors.Wrap(appErr, "unable to get audit entry in database")
+		}
+		if entryBytes == nil {
+			continue
+		}
+
+		var entry AuditEntry
+		err = json.Unmarshal(entryBytes, &entry)
+		if err != nil {
+			p.API.LogError(errors.Wrapf(err, "unable to parse audit entry %s", key).Error())
+			continue
+		}
+		if userID != "" && !entry.Involves(userID) {
+			continue
+		}
+		entries = append(entries, &entry)
+	}
+
+	return entries, nil
+}
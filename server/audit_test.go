@@ -0,0 +1,36 @@
+package main
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditLogKeyOrder(t *testing.T) {
+	entries := []*AuditEntry{
+		{ID: "c", Timestamp: 1571000000000},
+		{ID: "a", Timestamp: 999},
+		{ID: "b", Timestamp: 1570000000000},
+	}
+
+	var keys []string
+	for _, entry := range entries {
+		keys = append(keys, auditLogKey(entry))
+	}
+	sort.Strings(keys)
+
+	assert.Equal(t, []string{
+		auditLogKey(entries[1]),
+		auditLogKey(entries[2]),
+		auditLogKey(entries[0]),
+	}, keys)
+}
+
+func TestAuditEntryInvolves(t *testing.T) {
+	entry := &AuditEntry{ActorID: "admin", UserID: "user"}
+
+	assert.True(t, entry.Involves("admin"))
+	assert.True(t, entry.Involves("user"))
+	assert.False(t, entry.Involves("other"))
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/mattermost/mattermost-server/model"
 )
@@ -21,8 +22,11 @@ func (p *Plugin) PostBotDM(userID, message string) error {
 		ChannelId: channel.Id,
 		Message:   message,
 	})
+	if appError != nil {
+		return appError
+	}
 
-	return appError
+	return nil
 }
 
 // PostToChannelByIDAsBot posts a message to the provided channel.
@@ -38,3 +42,23 @@ func (p *Plugin) PostToChannelByIDAsBot(channelID, message string) error {
 
 	return nil
 }
+
+func (p *Plugin) getSiteURL() string {
+	siteURL := p.API.GetConfig().ServiceSettings.SiteURL
+	if siteURL == nil {
+		return ""
+	}
+
+	return strings.TrimSuffix(*siteURL, "/")
+}
+
+// getPluginURL returns the absolute URL of a plugin HTTP route, as used by
+// interactive message buttons.
+func (p *Plugin) getPluginURL(path string) string {
+	return fmt.Sprintf("%s/plugins/%s%s", p.getSiteURL(), manifest.ID, path)
+}
+
+// getPermalink returns a link to a post.
+func (p *Plugin) getPermalink(postID string) string {
+	return fmt.Sprintf("%s/_redirect/pl/%s", p.getSiteURL(), postID)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostBotDM(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("GetDirectChannel", "user1", "bot").Return(&model.Channel{Id: "dm"}, nil)
+	api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.UserId == "bot" && post.ChannelId == "dm" && post.Message == "hello"
+	})).Return(&model.Post{Id: "post"}, nil)
+	defer api.AssertExpectations(t)
+
+	p := newTestPlugin(api)
+	p.BotUserID = "bot"
+
+	err := p.PostBotDM("user1", "hello")
+	assert.True(t, err == nil, "a successful post must return a nil error interface")
+}
+
+func TestPostBotDMError(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("GetDirectChannel", "user1", "bot").Return(&model.Channel{Id: "dm"}, nil)
+	api.On("CreatePost", mock.Anything).Return(nil, model.NewAppError("CreatePost", "error", nil, "", 500))
+	defer api.AssertExpectations(t)
+
+	p := newTestPlugin(api)
+	p.BotUserID = "bot"
+
+	assert.Error(t, p.PostBotDM("user1", "hello"))
+}
@@ -11,12 +11,30 @@ import (
 const helpText = `* |/steam connect| - Connect your Mattermost account to your Steam account
 * |/steam disconnect| - Disconnect your Mattermost account from your Steam account
 * |/steam list| - Shows the list of games in your Steam library
-* |/steam recent| - Shows recent game stats about other Steam plugin users
-* |/steam compare [user1] [user2] [user3] [etc.]| - Compare owned games with one or multiple other Steam plugin users
+* |/steam recent [--since 30d/4w/3m/YYYY-MM-DD] [--until YYYY-MM-DD] [--server/--team/--channel] [--players/--by-user]| - Shows recent game stats about other Steam plugin users on the server, or only in the current team or channel. |--players| lists who played each game and |--by-user| shows each player's total and top game
+* |/steam compare [--achievements] [user1] [user2] [user3] [etc.]| - Compare owned games with one or multiple other Steam plugin users
+* |/steam alerts [add/remove] [game/wishlist]| - Manage price alerts for games or your wishlist
+* |/steam achievements [game] [@user]| - Shows achievements for a game for you or another Steam plugin user
+* |/steam channel [create/list/unlink] [game]| - Manage channels dedicated to games in the current team
+* |/steam event [create/list]| - Schedule game nights with RSVPs in the current channel
+* |/steam friends| - Shows which of your Steam friends are on Mattermost and who you might want to add
+* |/steam game [name or app ID]| - Shows details for a game and which Steam plugin users own it
+* |/steam lfg [game] [--slots N] [--note text]| - Look for players for a game in the current channel
+* |/steam play [user1] [user2] [etc.]| - Suggest games to play together with other Steam plugin users or the current channel
+* |/steam wishlist [user1] [user2] [etc.]| - Shows games wanted by several Steam plugin users or members of the current channel
+* |/steam top [--recent] [game]| - Shows playtime leaderboards for a game or for all games
+* |/steam subscribe news [game] [--feed name]| - Post news for a game in the current channel
+* |/steam subscriptions [remove game]| - List or remove news subscriptions in the current channel
+* |/steam wrapped [server] [year]| - Shows a yearly summary of your playtime, or of everyone on the server
+* |/steam settings| - Shows your user settings
 * |/steam settings [setting] [value]| - Update your user settings
-  * |setting| can be "show-profile"
+  * |setting| can be "show-profile", "show-activity", "hide-library", "hide-from-stats", "announce-presence", "announce-achievements" or "notify-lfg"
   * |value| can be "true" or "false"
-* |/steam info| - Shows plugin information`
+* |/steam settings edit| - Edit all of your user settings in a dialog
+* |/steam settings hide-game [game]| - Hide a game from everything shown to other users
+* |/steam settings show-game [game]| - Stop hiding a game
+* |/steam info| - Shows plugin information
+* |/steam admin| - Manage connected users (system admins only)`
 
 func getHelp() string {
 	return strings.Replace(helpText, "|", "`", -1)
@@ -28,7 +46,7 @@ func getCommand() *model.Command {
 		DisplayName:      "Steam",
 		Description:      "Integration with Steam",
 		AutoComplete:     true,
-		AutoCompleteDesc: "Available commands: connect, disconnect, recent, compare, settings, info",
+		AutoCompleteDesc: "Available commands: connect, disconnect, recent, compare, achievements, alerts, channel, event, friends, game, lfg, play, subscribe, subscriptions, top, wishlist, wrapped, settings, info, admin",
 		AutoCompleteHint: "[command]",
 	}
 }
@@ -44,17 +62,13 @@ func getCommandResponse(responseType, text string) *model.CommandResponse {
 
 // ExecuteCommand executes a given command and returns a command response.
 func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
-	config := p.getConfiguration()
-
-	if config.AllowedEmailDomain != "" {
-		user, err := p.API.GetUser(args.UserId)
-		if err != nil {
-			return nil, err
-		}
-
-		if !strings.HasSuffix(user.Email, config.AllowedEmailDomain) {
-			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Permission denied. Please talk to your system administrator to get access."), nil
-		}
+	denied, err := p.checkUserAccess(args.UserId)
+	if err != nil {
+		p.API.LogError(err.Error())
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Unable to check your access to the Steam plugin. Please try again later."), nil
+	}
+	if denied != "" {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, denied), nil
 	}
 
 	stringArgs := strings.Split(args.Command, " ")
@@ -78,10 +92,38 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 		handler = p.runCompareGamesCommand
 	case "recent":
 		handler = p.runListRecentGamesCommand
+	case "achievements":
+		handler = p.runAchievementsCommand
+	case "alerts":
+		handler = p.runAlertsCommand
+	case "channel":
+		handler = p.runChannelCommand
+	case "event":
+		handler = p.runEventCommand
+	case "friends":
+		handler = p.runFriendsCommand
+	case "game":
+		handler = p.runGameCommand
+	case "lfg":
+		handler = p.runLFGCommand
+	case "play":
+		handler = p.runPlayCommand
+	case "subscribe":
+		handler = p.runSubscribeCommand
+	case "subscriptions":
+		handler = p.runSubscriptionsCommand
+	case "top":
+		handler = p.runTopCommand
+	case "wishlist":
+		handler = p.runWishlistCommand
+	case "wrapped":
+		handler = p.runWrappedCommand
 	case "settings":
 		handler = p.runSettingsCommand
 	case "info":
 		handler = p.runInfoCommand
+	case "admin":
+		handler = p.runAdminCommand
 	}
 
 	if handler == nil {
@@ -107,9 +149,9 @@ func (p *Plugin) runInfoCommand(args []string, extra *model.CommandArgs) (*model
 		"[%s](https://github.com/gabrieljackson/mattermost-plugin-steam/commit/%s), built %s\n\n",
 		manifest.Version, BuildHashShort, BuildHash, BuildDate)
 
-	keys, appErr := p.API.KVList(0, 1000)
-	if appErr != nil {
-		return nil, false, appErr
+	keys, err := p.getAllKVKeys()
+	if err != nil {
+		return nil, false, err
 	}
 	keys = removeNonPlayerKVKeys(keys)
 
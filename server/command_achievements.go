@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/pkg/errors"
+)
+
+// achievementsListLimit is the maximum number of achievements listed.
+const achievementsListLimit = 25
+
+func (p *Plugin) runAchievementsCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	if len(args) == 0 {
+		return nil, true, errors.New("you must provide a game name or app ID")
+	}
+
+	userID := extra.UserId
+	access := accessSelf
+	subject := "You have"
+	var username string
+	if last := args[len(args)-1]; strings.HasPrefix(last, "@") {
+		username = strings.TrimPrefix(last, "@")
+		user, appErr := p.API.GetUserByUsername(username)
+		if appErr != nil {
+			return nil, true, errors.Wrapf(appErr, "unable to get user %s", username)
+		}
+
+		_, err := p.getSteamUserInfoByID(user.Id)
+		if err != nil {
+			return nil, true, fmt.Errorf("%s has not connected a Steam account", username)
+		}
+		if user.Id != extra.UserId {
+			access = accessLibrary
+		}
+
+		userID = user.Id
+		subject = fmt.Sprintf("@%s has", username)
+		args = args[:len(args)-1]
+	}
+
+	gameMap, err := p.getOwnedGamesForUser(userID, access)
+	if err == errSteamDataPrivate {
+		return nil, true, fmt.Errorf("%s's Steam library is private", username)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	game, err := ResolveGame(strings.Join(args, " "), gameMap)
+	if err != nil {
+		return nil, true, err
+	}
+
+	progress, err := p.getAchievementProgress(userID, game.AppID, access)
+	if err == errSteamDataPrivate {
+		return nil, true, fmt.Errorf("%s's achievements for this game are private", username)
+	}
+	if err != nil && err != errNoAchievements {
+		return nil, false, err
+	}
+	if game.Name == "" && progress != nil {
+		game.Name = progress.GameName
+	}
+
+	if progress == nil || len(progress.Achievements) == 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("[%s](%s) has no achievements.", game.Name, game.StoreLink())), false, nil
+	}
+
+	output := fmt.Sprintf("#### [%s](%s) achievements\n\n", game.Name, game.StoreLink())
+	output += fmt.Sprintf("%s unlocked %s achievements.\n\n", subject, progress.Summary())
+	output += "| Achievement | Unlocked | Global |\n"
+	output += "| :-- | :-- | --: |\n"
+	for i, achievement := range progress.Achievements {
+		if i == achievementsListLimit {
+			break
+		}
+
+		unlocked := "Locked"
+		if achievement.Achieved {
+			unlocked = "Yes"
+			if !achievement.UnlockTime.IsZero() {
+				unlocked = achievement.UnlockTime.Format("2006-01-02")
+			}
+		}
+		output += fmt.Sprintf("| **%s** %s | %s | %.1f%% |\n", tableCell(achievement.Name), tableCell(achievement.Description), unlocked, achievement.GlobalPercent)
+	}
+	if len(progress.Achievements) > achievementsListLimit {
+		output += fmt.Sprintf("\n%d more achievements not shown.\n", len(progress.Achievements)-achievementsListLimit)
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, output), false, nil
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/pkg/errors"
+)
+
+const adminMessage = `Usage:
+ - |/steam admin list| - List connected users with their Steam ID, when they connected and their last successful Steam API call
+ - |/steam admin disconnect [user]| - Disconnect a user's Steam account
+ - |/steam admin refresh [user]| - Drop a user's cached Steam profile and fetch it again
+ - |/steam admin purge-cache| - Drop all cached Steam data
+ - |/steam admin audit [user] [--json]| - Show recent account and settings changes, optionally only for one user or as JSON
+`
+
+const (
+	// adminTimeFormat is the format used to display times to admins.
+	adminTimeFormat = "2006-01-02 15:04 MST"
+
+	// auditDisplayEntries is the number of recent audit entries shown.
+	auditDisplayEntries = 50
+)
+
+func getAdminMessage() string {
+	return strings.Replace(adminMessage, "|", "`", -1)
+}
+
+func (p *Plugin) runAdminCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	if !p.API.HasPermissionTo(extra.UserId, model.PERMISSION_MANAGE_SYSTEM) {
+		return nil, true, errors.New("only system admins can run admin commands")
+	}
+	if len(args) == 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getAdminMessage()), false, nil
+	}
+
+	switch args[0] {
+	case "list":
+		return p.runAdminListCommand(extra)
+	case "disconnect":
+		return p.runAdminDisconnectCommand(args[1:], extra)
+	case "refresh":
+		return p.runAdminRefreshCommand(args[1:], extra)
+	case "purge-cache":
+		return p.runAdminPurgeCacheCommand(extra)
+	case "audit":
+		return p.runAdminAuditCommand(args[1:], extra)
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getAdminMessage()), false, nil
+}
+
+// getAdminTargetUser returns the user named in an admin command.
+func (p *Plugin) getAdminTargetUser(args []string) (*model.User, error) {
+	if len(args) == 0 {
+		return nil, errors.New("you must provide a username")
+	}
+
+	username := strings.TrimPrefix(args[0], "@")
+	user, appErr := p.API.GetUserByUsername(username)
+	if appErr != nil {
+		return nil, fmt.Errorf("no user found with username %s", username)
+	}
+
+	return user, nil
+}
+
+func (p *Plugin) runAdminListCommand(extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	userInfos, err := p.getSteamUsers()
+	if err != nil {
+		return nil, false, err
+	}
+	if len(userInfos) == 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "No users have connected a Steam account."), false, nil
+	}
+
+	location := p.getUserLocation(extra.UserId)
+
+	output := fmt.Sprintf("%d connected users:\n\n", len(userInfos))
+	output += "| User | Steam ID | Connected | Last Successful Call |\n"
+	output += "|:-----|:---------|:----------|:---------------------|\n"
+	for _, userInfo := range userInfos {
+		username := userInfo.MattermostUserID
+		if user, appErr := p.API.GetUser(userInfo.MattermostUserID); appErr == nil {
+			username = "@" + user.Username
+		}
+
+		connected := "Unknown"
+		if userInfo.ConnectedAt > 0 {
+			connected = time.Unix(0, userInfo.ConnectedAt*int64(time.Millisecond)).In(location).Format(adminTimeFormat)
+		}
+
+		lastCall := "Never"
+		lastCallTime, err := p.getLastSteamCall(userInfo.MattermostUserID)
+		if err != nil {
+			p.API.LogError(err.Error())
+			lastCall = "Unknown"
+		} else if !lastCallTime.IsZero() {
+			lastCall = lastCallTime.In(location).Format(adminTimeFormat)
+		}
+
+		output += fmt.Sprintf("| %s | %s | %s | %s |\n", username, userInfo.SteamID, connected, lastCall)
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, output), false, nil
+}
+
+func (p *Plugin) runAdminDisconnectCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	user, err := p.getAdminTargetUser(args)
+	if err != nil {
+		return nil, true, err
+	}
+
+	_, err = p.getSteamUserInfoByID(user.Id)
+	if err != nil {
+		return nil, true, fmt.Errorf("%s has not connected a Steam account", user.Username)
+	}
+
+	err = p.deleteSteamUser(user.Id)
+	if err != nil {
+		return nil, false, err
+	}
+	p.recordAudit(extra.UserId, user.Id, AuditActionAdminDisconnect, nil)
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Disconnected the Steam account of @%s.", user.Username)), false, nil
+}
+
+func (p *Plugin) runAdminRefreshCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	user, err := p.getAdminTargetUser(args)
+	if err != nil {
+		return nil, true, err
+	}
+
+	_, err = p.getSteamUserInfoByID(user.Id)
+	if err != nil {
+		return nil, true, fmt.Errorf("%s has not connected a Steam account", user.Username)
+	}
+
+	appErr := p.API.KVDelete(UserInfoCacheKey + user.Id)
+	if appErr != nil {
+		return nil, false, errors.Wrap(appErr, "unable to delete cached user info in database")
+	}
+	p.recordAudit(extra.UserId, user.Id, AuditActionAdminRefresh, nil)
+
+	response, err := p.getSteamUserInfoResponse(user.Id)
+	if err != nil {
+		return nil, true, errors.Wrapf(err, "unable to fetch the Steam profile of %s", user.Username)
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Refreshed the Steam profile of @%s: [%s](%s).", user.Username, response.PersonaName, response.ProfileURL)), false, nil
+}
+
+func (p *Plugin) runAdminPurgeCacheCommand(extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	purged, err := p.purgeSteamCaches()
+	if err != nil {
+		return nil, false, err
+	}
+	p.recordAudit(extra.UserId, "", AuditActionAdminPurgeCache, map[string]string{"purged": strconv.Itoa(purged)})
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Purged %d cached entries.", purged)), false, nil
+}
+
+func (p *Plugin) runAdminAuditCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	var exportJSON bool
+	var userArgs []string
+	for _, arg := range args {
+		if arg == "--json" {
+			exportJSON = true
+			continue
+		}
+		userArgs = append(userArgs, arg)
+	}
+
+	var userID string
+	if len(userArgs) > 0 {
+		user, err := p.getAdminTargetUser(userArgs)
+		if err != nil {
+			return nil, true, err
+		}
+		userID = user.Id
+	}
+
+	entries, err := p.getAuditEntries(userID)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(entries) == 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "No audit entries found."), false, nil
+	}
+
+	shown := entries
+	if len(shown) > auditDisplayEntries {
+		shown = shown[len(shown)-auditDisplayEntries:]
+	}
+
+	if exportJSON {
+		entriesBytes, err := json.MarshalIndent(shown, "", "  ")
+		if err != nil {
+			return nil, false, errors.Wrap(err, "unable to marshal audit entries")
+		}
+
+		output := fmt.Sprintf("Showing the latest %d of %d audit entries. The full log can be downloaded from %s\n\n```json\n%s\n```", len(shown), len(entries), p.getPluginURL(auditExportPath), entriesBytes)
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, output), false, nil
+	}
+
+	location := p.getUserLocation(extra.UserId)
+	usernames := make(map[string]string)
+	getUsername := func(id string) string {
+		if id == "" {
+			return ""
+		}
+		if _, ok := usernames[id]; !ok {
+			usernames[id] = id
+			if user, appErr := p.API.GetUser(id); appErr == nil {
+				usernames[id] = "@" + user.Username
+			}
+		}
+		return usernames[id]
+	}
+
+	output := fmt.Sprintf("Showing the latest %d of %d audit entries:\n\n", len(shown), len(entries))
+	output += "| Time | Actor | User | Action | Details |\n"
+	output += "|:-----|:------|:-----|:-------|:--------|\n"
+	for i := len(shown) - 1; i >= 0; i-- {
+		entry := shown[i]
+
+		var details []string
+		for key, value := range entry.Details {
+			details = append(details, fmt.Sprintf("%s=%s", key, value))
+		}
+		sort.Strings(details)
+
+		timestamp := time.Unix(0, entry.Timestamp*int64(time.Millisecond)).In(location).Format(adminTimeFormat)
+		output += fmt.Sprintf("| %s | %s | %s | %s | %s |\n", timestamp, getUsername(entry.ActorID), getUsername(entry.UserID), entry.Action, strings.Join(details, ", "))
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, output), false, nil
+}
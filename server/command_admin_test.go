@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunAdminCommandRequiresSystemAdmin(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("HasPermissionTo", "user1", model.PERMISSION_MANAGE_SYSTEM).Return(false)
+	defer api.AssertExpectations(t)
+
+	_, userError, err := newTestPlugin(api).runAdminCommand([]string{"purge-cache"}, &model.CommandArgs{UserId: "user1"})
+	assert.True(t, userError)
+	assert.EqualError(t, err, "only system admins can run admin commands")
+}
+
+func TestRunAdminPurgeCacheCommand(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("HasPermissionTo", "admin", model.PERMISSION_MANAGE_SYSTEM).Return(true)
+	api.On("KVList", 0, kvListPerPage).Return([]string{UserInfoCacheKey + "user1", UserInfoSummaryCacheKey + "user2", "user1" + SteamUserKey}, nil)
+	api.On("KVDelete", UserInfoCacheKey+"user1").Return(nil)
+	api.On("KVDelete", UserInfoSummaryCacheKey+"user2").Return(nil)
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil).Once()
+	defer api.AssertExpectations(t)
+
+	response, userError, err := newTestPlugin(api).runAdminCommand([]string{"purge-cache"}, &model.CommandArgs{UserId: "admin"})
+	assert.NoError(t, err)
+	assert.False(t, userError)
+	assert.Equal(t, "Purged 2 cached entries.", response.Text)
+}
+
+func TestRunAdminAuditCommandJSONShowsLatestEntries(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("HasPermissionTo", "admin", model.PERMISSION_MANAGE_SYSTEM).Return(true)
+
+	var keys []string
+	for i := 1; i <= auditDisplayEntries+10; i++ {
+		entry := &AuditEntry{ID: fmt.Sprintf("entry%d", i), Timestamp: int64(i), ActorID: "user1", Action: AuditActionConnect}
+		entryBytes, err := json.Marshal(entry)
+		require.NoError(t, err)
+
+		key := auditLogKey(entry)
+		keys = append(keys, key)
+		api.On("KVGet", key).Return(entryBytes, nil)
+	}
+	api.On("KVList", 0, kvListPerPage).Return(keys, nil)
+
+	siteURL := "https://chat.example.com"
+	api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+	defer api.AssertExpectations(t)
+
+	response, userError, err := newTestPlugin(api).runAdminCommand([]string{"audit", "--json"}, &model.CommandArgs{UserId: "admin"})
+	require.NoError(t, err)
+	assert.False(t, userError)
+
+	assert.Contains(t, response.Text, fmt.Sprintf("Showing the latest %d of %d audit entries.", auditDisplayEntries, auditDisplayEntries+10))
+	assert.Contains(t, response.Text, siteURL+"/plugins/"+manifest.ID+auditExportPath)
+	assert.Contains(t, response.Text, `"id": "entry11"`)
+	assert.Contains(t, response.Text, fmt.Sprintf(`"id": "entry%d"`, auditDisplayEntries+10))
+	assert.NotContains(t, response.Text, `"id": "entry10"`)
+	assert.Equal(t, auditDisplayEntries, strings.Count(response.Text, `"id": `))
+}
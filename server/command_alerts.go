@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/pkg/errors"
+)
+
+const alertsMessage = `Usage:
+ - |/steam alerts| - List your price alerts
+ - |/steam alerts add [game/wishlist] [--discount percent] [--price amount]| - Get a DM when a game, or any game on your wishlist, goes on sale
+ - |/steam alerts remove [game/wishlist]| - Remove a price alert
+`
+
+func getAlertsMessage() string {
+	return strings.Replace(alertsMessage, "|", "`", -1)
+}
+
+func (p *Plugin) runAlertsCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	if len(args) == 0 {
+		return p.runListAlertsCommand(extra)
+	}
+
+	switch args[0] {
+	case "add":
+		return p.runAddAlertCommand(args[1:], extra)
+	case "remove":
+		return p.runRemoveAlertCommand(args[1:], extra)
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getAlertsMessage()), false, nil
+}
+
+func (p *Plugin) runListAlertsCommand(extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	alerts, err := p.getPriceAlerts(extra.UserId)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(alerts) == 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "You have no price alerts.\n\n"+getAlertsMessage()), false, nil
+	}
+
+	output := "Your price alerts:\n"
+	for _, alert := range alerts {
+		output += fmt.Sprintf(" - %s\n", alert.Description())
+	}
+	if !p.getConfiguration().PriceAlertsEnable {
+		output += "\nPrice alerts are currently disabled by your system administrator.\n"
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, output), false, nil
+}
+
+func (p *Plugin) runAddAlertCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	alert := &PriceAlert{}
+
+	var gameArgs []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--discount":
+			if i+1 >= len(args) {
+				return nil, true, errors.New("--discount requires a percentage")
+			}
+			discount, err := strconv.Atoi(strings.TrimSuffix(args[i+1], "%"))
+			if err != nil || discount < 1 || discount > 100 {
+				return nil, true, fmt.Errorf("%s is not a valid discount, must be between 1 and 100", args[i+1])
+			}
+			alert.TargetDiscount = discount
+			i++
+		case "--price":
+			if i+1 >= len(args) {
+				return nil, true, errors.New("--price requires an amount")
+			}
+			price, err := strconv.ParseFloat(args[i+1], 64)
+			if err != nil || price <= 0 {
+				return nil, true, fmt.Errorf("%s is not a valid price", args[i+1])
+			}
+			alert.TargetPrice = int64(math.Round(price * 100))
+			i++
+		default:
+			gameArgs = append(gameArgs, args[i])
+		}
+	}
+	if len(gameArgs) == 0 {
+		return nil, true, errors.New("you must provide a game name, app ID or wishlist")
+	}
+	if alert.TargetDiscount > 0 && alert.TargetPrice > 0 {
+		return nil, true, errors.New("only one of --discount or --price can be provided")
+	}
+
+	if len(gameArgs) == 1 && gameArgs[0] == "wishlist" {
+		alert.Wishlist = true
+	} else {
+		gameMap, err := p.getOwnedGamesForUser(extra.UserId, accessSelf)
+		if err != nil {
+			return nil, false, err
+		}
+
+		game, err := ResolveGame(strings.Join(gameArgs, " "), gameMap)
+		if err != nil {
+			return nil, true, err
+		}
+		if game.Name == "" {
+			err = game.PopulateStoreData()
+			if err != nil {
+				return nil, false, err
+			}
+			game.Name = game.StoreData.Name
+		}
+		if game.Name == "" {
+			return nil, true, fmt.Errorf("no game found with app ID %d", game.AppID)
+		}
+
+		alert.AppID = game.AppID
+		alert.Name = game.Name
+	}
+
+	alerts, err := p.getPriceAlerts(extra.UserId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Replace any existing alert for the same game or wishlist.
+	var updated []*PriceAlert
+	for _, existing := range alerts {
+		if existing.Wishlist == alert.Wishlist && existing.AppID == alert.AppID {
+			continue
+		}
+		updated = append(updated, existing)
+	}
+	updated = append(updated, alert)
+
+	err = p.storePriceAlerts(extra.UserId, updated)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Price alert added for %s.", alert.Description())), false, nil
+}
+
+func (p *Plugin) runRemoveAlertCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	if len(args) == 0 {
+		return nil, true, errors.New("you must provide a game name, app ID or wishlist")
+	}
+
+	alerts, err := p.getPriceAlerts(extra.UserId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	wishlist := len(args) == 1 && args[0] == "wishlist"
+
+	var game *Game
+	if !wishlist {
+		alertGames := make(map[int64]Game)
+		for _, alert := range alerts {
+			if !alert.Wishlist {
+				alertGames[alert.AppID] = Game{AppID: alert.AppID, Name: alert.Name}
+			}
+		}
+
+		game = FindGameInLibraries(strings.Join(args, " "), alertGames)
+		if game == nil {
+			return nil, true, fmt.Errorf("you have no price alert for %s", strings.Join(args, " "))
+		}
+	}
+
+	var updated []*PriceAlert
+	var removed *PriceAlert
+	for _, alert := range alerts {
+		if (wishlist && alert.Wishlist) || (!wishlist && !alert.Wishlist && alert.AppID == game.AppID) {
+			removed = alert
+			continue
+		}
+		updated = append(updated, alert)
+	}
+	if removed == nil {
+		return nil, true, fmt.Errorf("you have no price alert for %s", strings.Join(args, " "))
+	}
+
+	err = p.storePriceAlerts(extra.UserId, updated)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Price alert removed for %s.", removed.Description())), false, nil
+}
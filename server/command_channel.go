@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/pkg/errors"
+)
+
+const channelMessage = `Usage:
+ - |/steam channel create [game] [--here] [--auto-invite]| - Create a channel for a game and invite connected users who own it. |--here| links the current channel instead and |--auto-invite| also invites new owners later on
+ - |/steam channel list| - List the game channels in this team
+ - |/steam channel unlink [game]| - Stop linking a channel to a game
+`
+
+func getChannelMessage() string {
+	return strings.Replace(channelMessage, "|", "`", -1)
+}
+
+func (p *Plugin) runChannelCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	if len(args) == 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getChannelMessage()), false, nil
+	}
+
+	switch args[0] {
+	case "create":
+		return p.runCreateChannelCommand(args[1:], extra)
+	case "list":
+		return p.runListChannelsCommand(extra)
+	case "unlink":
+		return p.runUnlinkChannelCommand(args[1:], extra)
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getChannelMessage()), false, nil
+}
+
+// canManageChannel returns true if the user can change the channel's
+// properties.
+func (p *Plugin) canManageChannel(userID string, channel *model.Channel) bool {
+	permission := model.PERMISSION_MANAGE_PUBLIC_CHANNEL_PROPERTIES
+	if channel.Type == model.CHANNEL_PRIVATE {
+		permission = model.PERMISSION_MANAGE_PRIVATE_CHANNEL_PROPERTIES
+	}
+
+	return p.API.HasPermissionToChannel(userID, channel.Id, permission)
+}
+
+func (p *Plugin) runCreateChannelCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	var here, autoInvite bool
+	var gameArgs []string
+	for _, arg := range args {
+		switch arg {
+		case "--here":
+			here = true
+		case "--auto-invite":
+			autoInvite = true
+		default:
+			gameArgs = append(gameArgs, arg)
+		}
+	}
+	if len(gameArgs) == 0 {
+		return nil, true, errors.New("you must provide a game name or app ID")
+	}
+
+	var libraries []map[int64]Game
+	if gameMap, libraryErr := p.getOwnedGamesForUser(extra.UserId, accessSelf); libraryErr == nil {
+		libraries = append(libraries, gameMap)
+	}
+	game, err := ResolveGame(strings.Join(gameArgs, " "), libraries...)
+	if err != nil {
+		return nil, true, err
+	}
+	if game.Name == "" {
+		err = game.PopulateStoreData()
+		if err != nil {
+			return nil, false, err
+		}
+		game.Name = game.StoreData.Name
+	}
+	if game.Name == "" {
+		return nil, true, fmt.Errorf("no game found with app ID %d", game.AppID)
+	}
+
+	existing, err := p.getGameChannel(extra.TeamId, game.AppID)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing != nil {
+		return nil, true, fmt.Errorf("%s is already linked to ~%s", game.Name, p.getChannelName(existing.ChannelID))
+	}
+
+	var channel *model.Channel
+	if here {
+		var appErr *model.AppError
+		channel, appErr = p.API.GetChannel(extra.ChannelId)
+		if appErr != nil {
+			return nil, false, errors.Wrap(appErr, "unable to get channel")
+		}
+		if channel.IsGroupOrDirect() {
+			return nil, true, errors.New("direct and group messages can't be linked to a game")
+		}
+		if !p.canManageChannel(extra.UserId, channel) {
+			return nil, true, errors.New("you don't have permission to manage this channel")
+		}
+	} else {
+		// An existing channel with the game's name is linked rather than
+		// creating a duplicate.
+		name := gameChannelName(game)
+		channel, _ = p.API.GetChannelByName(extra.TeamId, name, false)
+		if channel != nil {
+			if channel.Type != model.CHANNEL_OPEN {
+				return nil, true, fmt.Errorf("~%s is private, run this command in it with --here to link it", channel.Name)
+			}
+			if !p.canManageChannel(extra.UserId, channel) {
+				return nil, true, fmt.Errorf("you don't have permission to manage ~%s", channel.Name)
+			}
+		} else {
+			if !p.API.HasPermissionToTeam(extra.UserId, extra.TeamId, model.PERMISSION_CREATE_PUBLIC_CHANNEL) {
+				return nil, true, errors.New("you don't have permission to create channels in this team")
+			}
+
+			displayName := game.Name
+			if runes := []rune(displayName); len(runes) > model.CHANNEL_DISPLAY_NAME_MAX_RUNES {
+				displayName = string(runes[:model.CHANNEL_DISPLAY_NAME_MAX_RUNES])
+			}
+
+			var appErr *model.AppError
+			channel, appErr = p.API.CreateChannel(&model.Channel{
+				TeamId:      extra.TeamId,
+				Type:        model.CHANNEL_OPEN,
+				Name:        name,
+				DisplayName: displayName,
+				Purpose:     fmt.Sprintf("Discussion about %s", game.Name),
+				Header:      fmt.Sprintf("[%s](%s)", game.Name, game.StoreLink()),
+				CreatorId:   extra.UserId,
+			})
+			if appErr != nil {
+				return nil, false, errors.Wrap(appErr, "unable to create channel")
+			}
+		}
+	}
+
+	gameChannel := &GameChannel{
+		AppID:          game.AppID,
+		GameName:       game.Name,
+		TeamID:         extra.TeamId,
+		ChannelID:      channel.Id,
+		CreatorID:      extra.UserId,
+		AutoInvite:     autoInvite,
+		InvitedUserIDs: []string{extra.UserId},
+	}
+
+	if _, appErr := p.API.GetChannelMember(channel.Id, extra.UserId); appErr != nil && channel.Type == model.CHANNEL_OPEN {
+		_, appErr = p.API.AddChannelMember(channel.Id, extra.UserId)
+		if appErr != nil {
+			p.API.LogError(errors.Wrapf(appErr, "unable to add %s to channel %s", extra.UserId, channel.Id).Error())
+		}
+	}
+
+	ownerLibraries, err := p.getGameOwnerLibraries(extra.TeamId)
+	if err != nil {
+		return nil, false, err
+	}
+	invited := p.inviteGameOwners(gameChannel, ownerLibraries)
+
+	err = p.storeGameChannel(gameChannel)
+	if err != nil {
+		return nil, false, err
+	}
+
+	output := fmt.Sprintf("~%s is now the channel for %s. Invited %d connected users who own the game.", channel.Name, game.Name, len(invited))
+	if autoInvite {
+		output += " New owners will be invited automatically."
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, output), false, nil
+}
+
+func (p *Plugin) runListChannelsCommand(extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	gameChannels, err := p.getGameChannels(extra.TeamId)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(gameChannels) == 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "There are no game channels in this team.\n\n"+getChannelMessage()), false, nil
+	}
+
+	output := "Game channels in this team:\n"
+	for _, gameChannel := range gameChannels {
+		game := Game{AppID: gameChannel.AppID, Name: gameChannel.GameName}
+		output += fmt.Sprintf(" - [%s](%s): ~%s", game.Name, game.StoreLink(), p.getChannelName(gameChannel.ChannelID))
+		if gameChannel.AutoInvite {
+			output += " [auto-invite]"
+		}
+		output += "\n"
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, output), false, nil
+}
+
+func (p *Plugin) runUnlinkChannelCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	if len(args) == 0 {
+		return nil, true, errors.New("you must provide a game name or app ID")
+	}
+
+	gameChannels, err := p.getGameChannels(extra.TeamId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	linkedGames := make(map[int64]Game)
+	for _, gameChannel := range gameChannels {
+		linkedGames[gameChannel.AppID] = Game{AppID: gameChannel.AppID, Name: gameChannel.GameName}
+	}
+	game := FindGameInLibraries(strings.Join(args, " "), linkedGames)
+	if game == nil {
+		return nil, true, fmt.Errorf("no channel is linked to %s in this team", strings.Join(args, " "))
+	}
+
+	gameChannel, err := p.getGameChannel(extra.TeamId, game.AppID)
+	if err != nil {
+		return nil, false, err
+	}
+	if gameChannel == nil {
+		return nil, true, fmt.Errorf("no channel is linked to %s in this team", game.Name)
+	}
+
+	if gameChannel.CreatorID != extra.UserId {
+		channel, appErr := p.API.GetChannel(gameChannel.ChannelID)
+		if appErr == nil && !p.canManageChannel(extra.UserId, channel) {
+			return nil, true, errors.New("you don't have permission to manage this channel")
+		}
+	}
+
+	err = p.deleteGameChannel(gameChannel)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("~%s is no longer linked to %s. The channel itself was not changed.", p.getChannelName(gameChannel.ChannelID), game.Name)), false, nil
+}
+
+// getChannelName returns the name of a channel, or its ID if it can't be
+// found.
+func (p *Plugin) getChannelName(channelID string) string {
+	channel, appErr := p.API.GetChannel(channelID)
+	if appErr != nil {
+		return channelID
+	}
+
+	return channel.Name
+}
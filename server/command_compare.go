@@ -2,22 +2,37 @@ package main
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/pkg/errors"
 )
 
+// compareAchievementsMaxGames is the maximum number of shared games that
+// achievements are compared for.
+const compareAchievementsMaxGames = 15
+
 func (p *Plugin) runCompareGamesCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
-	if len(args) == 0 {
+	var achievements bool
+	var usernames []string
+	for _, arg := range args {
+		if arg == "--achievements" {
+			achievements = true
+			continue
+		}
+		usernames = append(usernames, arg)
+	}
+
+	if len(usernames) == 0 {
 		return nil, true, errors.New("you must provide a list of usernames to compare game lists against")
 	}
-	if len(args) > 10 {
+	if len(usernames) > 10 {
 		return nil, true, errors.New("the compare command is currently limited to 10 users")
 	}
 
 	var userList []string
-	for _, arg := range args {
+	for _, arg := range usernames {
 		user, err := p.API.GetUserByUsername(arg)
 		if err != nil {
 			return nil, true, errors.Wrapf(err, "unable to get user %s", arg)
@@ -27,39 +42,94 @@ func (p *Plugin) runCompareGamesCommand(args []string, extra *model.CommandArgs)
 	}
 
 	// Start by getting your game list.
-	result, err := p.makeSteamAPICall(extra.UserId+SteamUserKey, steamAPIGetOwnedGames)
+	masterList, err := p.getOwnedGamesForUser(extra.UserId, accessSelf)
 	if err != nil {
 		return nil, false, err
 	}
-
-	masterList, err := MakeGameMapFromRawGameListResponse(result)
-	if err != nil {
-		return nil, false, err
+	playtimes := make(map[int64]int64)
+	for appID, game := range masterList {
+		playtimes[appID] = game.Playtime
 	}
 
-	for _, userID := range userList {
-		result, err := p.makeSteamAPICall(userID+SteamUserKey, steamAPIGetOwnedGames)
-		if err != nil {
-			return nil, false, err
+	for i, userID := range userList {
+		gameMap, err := p.getOwnedGamesForUser(userID, accessLibrary)
+		if err == errSteamDataPrivate {
+			return nil, true, fmt.Errorf("%s's Steam library is private", usernames[i])
 		}
-
-		gameMap, err := MakeGameMapFromRawGameListResponse(result)
 		if err != nil {
 			return nil, false, err
 		}
 
 		for appID := range masterList {
-			if _, ok := gameMap[appID]; !ok {
+			game, ok := gameMap[appID]
+			if !ok {
 				delete(masterList, appID)
+				continue
 			}
+			playtimes[appID] += game.Playtime
 		}
 	}
 
-	output := fmt.Sprintf("Games owned by you and %s\n", strings.Join(args, ", "))
+	output := fmt.Sprintf("Games owned by you and %s\n", strings.Join(usernames, ", "))
 	output += fmt.Sprintf("Total: %d\n", len(masterList))
+
+	if !achievements {
+		for _, game := range masterList {
+			output += fmt.Sprintf(" - [%s](%s)\n", game.Name, game.StoreLink())
+		}
+
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, output), false, nil
+	}
+
+	// Achievements are one request per user per game, so only the most played
+	// shared games are compared.
+	var games []Game
 	for _, game := range masterList {
-		output += fmt.Sprintf(" - [%s](%s)\n", game.Name, game.StoreLink())
+		games = append(games, game)
+	}
+	sort.Slice(games, func(i, j int) bool { return playtimes[games[i].AppID] > playtimes[games[j].AppID] })
+	if len(games) > compareAchievementsMaxGames {
+		output += fmt.Sprintf("Showing achievements for the %d most played games.\n", compareAchievementsMaxGames)
+		games = games[:compareAchievementsMaxGames]
+	}
+
+	summaries := make(map[int64][]string)
+	for _, game := range games {
+		for _, userID := range append([]string{extra.UserId}, userList...) {
+			access := accessLibrary
+			if userID == extra.UserId {
+				access = accessSelf
+			}
+
+			summary := "?"
+			stats, err := p.getPlayerAchievements(userID, game.AppID, access)
+			if err == errNoAchievements {
+				summary = "-"
+			} else if err != nil {
+				p.API.LogError(errors.Wrapf(err, "unable to get achievements for %s", userID).Error())
+			} else {
+				summary = stats.Summary()
+			}
+			summaries[game.AppID] = append(summaries[game.AppID], summary)
+		}
 	}
+	output += makeAchievementCompareTable(games, usernames, summaries)
 
 	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, output), false, nil
 }
+
+// makeAchievementCompareTable returns a table of achievement summaries with
+// a row per game and a column for the user followed by each other user.
+func makeAchievementCompareTable(games []Game, usernames []string, summaries map[int64][]string) string {
+	output := fmt.Sprintf("\n| Game | you | %s |\n", strings.Join(usernames, " | "))
+	output += "| :-- |" + strings.Repeat(" :-- |", len(usernames)+1) + "\n"
+	for _, game := range games {
+		output += fmt.Sprintf("| [%s](%s) |", tableCell(game.Name), game.StoreLink())
+		for _, summary := range summaries[game.AppID] {
+			output += fmt.Sprintf(" %s |", summary)
+		}
+		output += "\n"
+	}
+
+	return output
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeAchievementCompareTable(t *testing.T) {
+	games := []Game{
+		{AppID: 10, Name: "Counter-Strike"},
+		{AppID: 20, Name: "Left | Right"},
+	}
+	summaries := map[int64][]string{
+		10: {"1/2", "2/2", "?"},
+		20: {"-", "-", "-"},
+	}
+
+	table := makeAchievementCompareTable(games, []string{"alice", "bob"}, summaries)
+	assert.Equal(t, "\n| Game | you | alice | bob |\n"+
+		"| :-- | :-- | :-- | :-- |\n"+
+		"| [Counter-Strike]("+games[0].StoreLink()+") | 1/2 | 2/2 | ? |\n"+
+		"| [Left \\| Right]("+games[1].StoreLink()+") | - | - | - |\n", table)
+}
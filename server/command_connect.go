@@ -3,62 +3,116 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"strings"
+	neturl "net/url"
+	"strconv"
 
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/pkg/errors"
 )
 
-const connectMessage = `Usage: |/steam connect [steam_ID] [steam_api_key]|
+const (
+	connectDialogPath       = "/api/v1/connect/dialog"
+	connectDialogCallbackID = "steam_connect"
+)
+
+const connectIntroduction = `Connect your Mattermost account to your Steam account.
 
  - Obtain your Steam ID by viewing your Steam profile. The ID will be shown in your profile URL.
  - Obtain your API key by using the following link: https://steamcommunity.com/dev/apikey
 `
 
-func getConnectMessage() string {
-	return strings.Replace(connectMessage, "|", "`", -1)
+const connectedMessage = "Steam account successfully connected!\n\n" +
+	"Your profile is hidden by default. " +
+	"Run `/steam settings show-profile true` to display your Steam " +
+	"profile in your Mattermost Profile"
+
+// makeConnectDialog returns the dialog used to enter Steam credentials, so
+// that the API key is never sent as a slash command argument.
+func makeConnectDialog() model.Dialog {
+	return model.Dialog{
+		CallbackId:       connectDialogCallbackID,
+		Title:            "Connect Steam Account",
+		IntroductionText: connectIntroduction,
+		SubmitLabel:      "Connect",
+		Elements: []model.DialogElement{
+			{
+				DisplayName: "Steam ID",
+				Name:        "steam_id",
+				Type:        "text",
+				Placeholder: "76561197960287930",
+			},
+			{
+				DisplayName: "Steam API Key",
+				Name:        "api_key",
+				Type:        "text",
+				SubType:     "password",
+			},
+		},
+	}
 }
 
 func (p *Plugin) runConnectCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
-	if len(args) < 2 {
-		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getConnectMessage()), false, nil
+	appErr := p.API.OpenInteractiveDialog(model.OpenDialogRequest{
+		TriggerId: extra.TriggerId,
+		URL:       p.getPluginURL(connectDialogPath),
+		Dialog:    makeConnectDialog(),
+	})
+	if appErr != nil {
+		return nil, false, errors.Wrap(appErr, "unable to open connect dialog")
 	}
-	steamID := args[0]
-	apiKey := args[1]
 
-	// Check that the values are valid.
-	url := fmt.Sprintf("https://api.steampowered.com/ISteamUser/GetPlayerSummaries/v0002/?key=%s&steamids=%s&format=json", apiKey, steamID)
+	// Credentials are no longer accepted as arguments, but users may still
+	// paste them out of habit.
+	if len(args) > 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Steam credentials are now entered in the dialog instead of the command. "+
+			"If you included your API key, consider revoking it at https://steamcommunity.com/dev/apikey and using a new one."), false, nil
+	}
+
+	return &model.CommandResponse{}, false, nil
+}
+
+// validateSteamCredentials checks that the API key can be used to look up the
+// Steam ID.
+func validateSteamCredentials(steamID, apiKey string) error {
+	url := fmt.Sprintf("https://api.steampowered.com/ISteamUser/GetPlayerSummaries/v0002/?key=%s&steamids=%s&format=json", neturl.QueryEscape(apiKey), neturl.QueryEscape(steamID))
 	result, err := steamAPICall(url)
 	if err != nil {
-		return nil, true, errors.Wrap(err, "Invalid Steam credentials")
+		return errors.Wrap(err, "Invalid Steam credentials")
 	}
 
 	var playerListResponse *PlayersListResponse
 	err = json.Unmarshal(result, &playerListResponse)
 	if err != nil {
-		return nil, true, errors.Wrap(err, "Invalid Steam credentials")
+		return errors.Wrap(err, "Invalid Steam credentials")
 	}
-
-	steamUser := &SteamUserInfo{
-		MattermostUserID: extra.UserId,
-		SteamID:          steamID,
-		APIToken:         apiKey,
-		Settings: &UserSettings{
-			ShowProfile: false,
-		},
+	if len(playerListResponse.Response.Players) == 0 {
+		return errors.New("no Steam account found with that Steam ID")
 	}
 
-	err = p.storeSteamUser(steamUser)
-	if err != nil {
-		return nil, false, err
-	}
+	return nil
+}
 
-	msg := "Steam account successfully connected!\n\n" +
-		"Your profile is hidden by default. " +
-		"Run `/steam settings show-profile true` to display your Steam " +
-		"profile in your Mattermost Profile"
+// isValidSteamID returns true if the Steam ID is a 64-bit Steam ID.
+func isValidSteamID(steamID string) bool {
+	_, err := strconv.ParseUint(steamID, 10, 64)
+	return err == nil
+}
+
+// connectSteamUser stores the Steam credentials for a user. The settings of
+// a user who reconnects are kept.
+func (p *Plugin) connectSteamUser(userID, steamID, apiKey string) error {
+	settings := defaultUserSettings()
+	if existing, err := p.getSteamUserInfoByID(userID); err == nil {
+		settings = existing.Settings
+	}
 
-	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, msg), false, nil
+	return p.storeSteamUser(&SteamUserInfo{
+		MattermostUserID: userID,
+		SteamID:          steamID,
+		APIToken:         apiKey,
+		Settings:         settings,
+		ConnectedAt:      model.GetMillis(),
+	})
 }
 
 func (p *Plugin) runDisconnectCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
@@ -66,6 +120,7 @@ func (p *Plugin) runDisconnectCommand(args []string, extra *model.CommandArgs) (
 	if err != nil {
 		return nil, false, err
 	}
+	p.recordAudit(extra.UserId, extra.UserId, AuditActionDisconnect, nil)
 
 	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Steam account successfully disconnected."), false, nil
 }
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsValidSteamID(t *testing.T) {
+	assert.True(t, isValidSteamID("76561197960287930"))
+	assert.False(t, isValidSteamID("gabelogannewell"))
+	assert.False(t, isValidSteamID("76561197960287930&key=other"))
+	assert.False(t, isValidSteamID(""))
+}
+
+func TestConnectSteamUser(t *testing.T) {
+	storedSettings := func(t *testing.T, api *plugintest.API) *UserSettings {
+		var settings *UserSettings
+		api.On("KVSet", "user1"+SteamUserKey, mock.Anything).Run(func(args mock.Arguments) {
+			var userInfo SteamUserInfo
+			require.NoError(t, json.Unmarshal(args.Get(1).([]byte), &userInfo))
+			assert.Equal(t, "76561197960287931", userInfo.SteamID)
+			settings = userInfo.Settings
+		}).Return(nil)
+
+		require.NoError(t, newTestPlugin(api).connectSteamUser("user1", "76561197960287931", "key"))
+		return settings
+	}
+
+	t.Run("new user gets default settings", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("KVGet", "user1"+SteamUserKey).Return(nil, nil)
+		defer api.AssertExpectations(t)
+
+		assert.Equal(t, defaultUserSettings(), storedSettings(t, api))
+	})
+
+	t.Run("reconnecting keeps settings", func(t *testing.T) {
+		settings := defaultUserSettings()
+		settings.ShowProfile = true
+		settings.HiddenGames = []int64{10}
+
+		api := &plugintest.API{}
+		api.On("KVGet", "user1"+SteamUserKey).Return(makeTestSteamUserBytes(t, "user1", settings), nil)
+		defer api.AssertExpectations(t)
+
+		assert.Equal(t, settings, storedSettings(t, api))
+	})
+}
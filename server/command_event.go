@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/pkg/errors"
+)
+
+const eventMessage = `Usage:
+ - |/steam event create [game] [time]| - Schedule a game night in this channel. |time| can be a duration like "2h", a time like "20:00" or a date and time like "2019-10-31 20:00"
+ - |/steam event list| - List upcoming game nights in this channel
+`
+
+func getEventMessage() string {
+	return strings.Replace(eventMessage, "|", "`", -1)
+}
+
+func (p *Plugin) runEventCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	if len(args) == 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getEventMessage()), false, nil
+	}
+
+	switch args[0] {
+	case "create":
+		return p.runCreateEventCommand(args[1:], extra)
+	case "list":
+		return p.runListEventsCommand(extra)
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getEventMessage()), false, nil
+}
+
+func (p *Plugin) runCreateEventCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	if len(args) < 2 {
+		return nil, true, errors.New("you must provide a game and a start time")
+	}
+
+	// The time is either the last argument or, for a date and time, the last
+	// two arguments.
+	location := p.getUserLocation(extra.UserId)
+	now := time.Now()
+	gameArgs := args[:len(args)-1]
+	startTime, err := parseEventTime(args[len(args)-1], now, location)
+	if len(args) > 2 {
+		if dateTime, dateErr := parseEventTime(strings.Join(args[len(args)-2:], " "), now, location); dateErr == nil {
+			gameArgs = args[:len(args)-2]
+			startTime, err = dateTime, nil
+		}
+	}
+	if err != nil {
+		return nil, true, err
+	}
+
+	var libraries []map[int64]Game
+	if gameMap, libraryErr := p.getOwnedGamesForUser(extra.UserId, accessSelf); libraryErr == nil {
+		libraries = append(libraries, gameMap)
+	}
+	game, err := ResolveGame(strings.Join(gameArgs, " "), libraries...)
+	if err != nil {
+		return nil, true, err
+	}
+	if game.Name == "" {
+		err = game.PopulateStoreData()
+		if err != nil {
+			return nil, false, err
+		}
+		game.Name = game.StoreData.Name
+	}
+	if game.Name == "" {
+		return nil, true, fmt.Errorf("no game found with app ID %d", game.AppID)
+	}
+
+	event := &GameEvent{
+		ID:        model.NewId(),
+		ChannelID: extra.ChannelId,
+		CreatorID: extra.UserId,
+		AppID:     game.AppID,
+		GameName:  game.Name,
+		StartTime: startTime.UTC(),
+		Timezone:  location.String(),
+	}
+	owns, ownsKnown := p.getGameOwnership(extra.UserId, game.AppID)
+	event.SetRSVP(extra.UserId, RSVPGoing, owns, ownsKnown)
+
+	post, appErr := p.API.CreatePost(p.makeGameEventPost(event))
+	if appErr != nil {
+		return nil, false, errors.Wrap(appErr, "unable to post event")
+	}
+	event.PostID = post.Id
+
+	_, err = p.storeGameEvent(event, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Game night for %s scheduled for %s.", game.Name, event.FormatStartTime())), false, nil
+}
+
+func (p *Plugin) runListEventsCommand(extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	events, err := p.getGameEvents()
+	if err != nil {
+		return nil, false, err
+	}
+
+	location := p.getUserLocation(extra.UserId)
+	now := time.Now()
+
+	var output string
+	for _, event := range events {
+		if event.ChannelID != extra.ChannelId || event.StartTime.Before(now) {
+			continue
+		}
+
+		game := Game{AppID: event.AppID, Name: event.GameName}
+		output += fmt.Sprintf(" - [%s](%s) on %s [%d going, %d maybe] [Event](%s)\n",
+			game.Name, game.StoreLink(), event.StartTime.In(location).Format(eventTimeFormat),
+			len(event.UserIDsWithRSVP(RSVPGoing)), len(event.UserIDsWithRSVP(RSVPMaybe)), p.getPermalink(event.PostID))
+	}
+	if output == "" {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "There are no upcoming game nights in this channel."), false, nil
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Upcoming game nights in this channel:\n"+output), false, nil
+}
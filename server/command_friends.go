@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// friendSuggestionMinSharedGames is the minimum number of shared games for
+	// a connected user to be suggested as a Steam friend.
+	friendSuggestionMinSharedGames = 5
+
+	// friendSuggestionLimit is the maximum number of friend suggestions.
+	friendSuggestionLimit = 5
+)
+
+// FriendListResponse is an API response for a player's friend list.
+type FriendListResponse struct {
+	FriendsList FriendsList `json:"friendslist"`
+}
+
+// FriendsList is a player's list of Steam friends.
+type FriendsList struct {
+	Friends []Friend `json:"friends"`
+}
+
+// Friend is a Steam friend.
+type Friend struct {
+	SteamID      string `json:"steamid"`
+	Relationship string `json:"relationship"`
+	FriendSince  int64  `json:"friend_since"`
+}
+
+type friendSuggestion struct {
+	Username    string
+	SteamID     string
+	SharedGames int
+}
+
+func (p *Plugin) getFriendList(userID string) ([]Friend, error) {
+	result, err := p.makeSteamAPICall(userID+SteamUserKey, steamAPIGetFriendList)
+	if err != nil {
+		return nil, err
+	}
+
+	var friendListResponse FriendListResponse
+	err = json.Unmarshal(result, &friendListResponse)
+	if err != nil {
+		return nil, errors.New("unable to get your Steam friend list, make sure your friend list is public")
+	}
+
+	return friendListResponse.FriendsList.Friends, nil
+}
+
+func (p *Plugin) runFriendsCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	friends, err := p.getFriendList(extra.UserId)
+	if err != nil {
+		return nil, true, err
+	}
+	friendSince := make(map[string]int64)
+	for _, friend := range friends {
+		friendSince[friend.SteamID] = friend.FriendSince
+	}
+
+	ownedGames, err := p.getOwnedGamesForUser(extra.UserId, accessSelf)
+	if err != nil {
+		return nil, false, err
+	}
+
+	userInfos, err := p.getSteamUsers()
+	if err != nil {
+		return nil, false, err
+	}
+
+	var connectedFriends []string
+	var suggestions []friendSuggestion
+	for _, userInfo := range userInfos {
+		if userInfo.MattermostUserID == extra.UserId {
+			continue
+		}
+
+		user, appErr := p.API.GetUser(userInfo.MattermostUserID)
+		if appErr != nil {
+			p.API.LogError(errors.Wrapf(appErr, "unable to get user %s", userInfo.MattermostUserID).Error())
+			continue
+		}
+
+		// Only list or suggest users who have chosen to show their Steam
+		// profile, so that hidden accounts can't be linked to a Steam ID.
+		if !userInfo.Settings.Allows(accessProfile) {
+			continue
+		}
+
+		if since, ok := friendSince[userInfo.SteamID]; ok {
+			connectedFriends = append(connectedFriends, formatFriendEntry(user.Username, since))
+			continue
+		}
+
+		// Suggestions also need the user to share their library.
+		gameMap, err := p.getOwnedGamesForUser(userInfo.MattermostUserID, accessLibrary)
+		if err == errSteamDataPrivate {
+			continue
+		}
+		if err != nil {
+			p.API.LogError(errors.Wrapf(err, "unable to get owned games for %s", userInfo.MattermostUserID).Error())
+			continue
+		}
+
+		shared := countSharedGames(ownedGames, gameMap)
+		if shared < friendSuggestionMinSharedGames {
+			continue
+		}
+
+		suggestions = append(suggestions, friendSuggestion{
+			Username:    user.Username,
+			SteamID:     userInfo.SteamID,
+			SharedGames: shared,
+		})
+	}
+	sort.Strings(connectedFriends)
+	suggestions = rankFriendSuggestions(suggestions)
+
+	output := fmt.Sprintf("#### Steam friends on Mattermost [%d]\n\n", len(connectedFriends))
+	if len(connectedFriends) == 0 {
+		output += "None of your Steam friends have connected their Steam account yet.\n"
+	}
+	for _, friend := range connectedFriends {
+		output += fmt.Sprintf(" - %s\n", friend)
+	}
+
+	if len(suggestions) > 0 {
+		output += "\n#### People you might want to add\n\n"
+		for _, suggestion := range suggestions {
+			output += fmt.Sprintf(" - @%s [%d shared games] [Add on Steam](https://steamcommunity.com/profiles/%s)\n",
+				suggestion.Username, suggestion.SharedGames, suggestion.SteamID)
+		}
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, output), false, nil
+}
+
+// formatFriendEntry returns how a connected Steam friend is listed.
+func formatFriendEntry(username string, since int64) string {
+	entry := fmt.Sprintf("@%s", username)
+	if since > 0 {
+		entry += fmt.Sprintf(" [friends since %s]", time.Unix(since, 0).UTC().Format("2006-01-02"))
+	}
+
+	return entry
+}
+
+// countSharedGames returns the number of games in gameMap that are also in
+// ownedGames.
+func countSharedGames(ownedGames, gameMap map[int64]Game) int {
+	var shared int
+	for appID := range gameMap {
+		if _, ok := ownedGames[appID]; ok {
+			shared++
+		}
+	}
+
+	return shared
+}
+
+// rankFriendSuggestions sorts suggestions by shared games, most first, and
+// limits them to friendSuggestionLimit.
+func rankFriendSuggestions(suggestions []friendSuggestion) []friendSuggestion {
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].SharedGames == suggestions[j].SharedGames {
+			return suggestions[i].Username < suggestions[j].Username
+		}
+		return suggestions[i].SharedGames > suggestions[j].SharedGames
+	})
+	if len(suggestions) > friendSuggestionLimit {
+		suggestions = suggestions[:friendSuggestionLimit]
+	}
+
+	return suggestions
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatFriendEntry(t *testing.T) {
+	assert.Equal(t, "@alice", formatFriendEntry("alice", 0))
+
+	since := time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC).Unix()
+	assert.Equal(t, "@alice [friends since 2015-06-01]", formatFriendEntry("alice", since))
+}
+
+func TestCountSharedGames(t *testing.T) {
+	owned := map[int64]Game{1: {}, 2: {}, 3: {}}
+
+	assert.Equal(t, 2, countSharedGames(owned, map[int64]Game{2: {}, 3: {}, 4: {}}))
+	assert.Equal(t, 0, countSharedGames(owned, nil))
+}
+
+func TestRankFriendSuggestions(t *testing.T) {
+	suggestions := rankFriendSuggestions([]friendSuggestion{
+		{Username: "f", SharedGames: 5},
+		{Username: "e", SharedGames: 6},
+		{Username: "d", SharedGames: 9},
+		{Username: "b", SharedGames: 7},
+		{Username: "a", SharedGames: 7},
+		{Username: "c", SharedGames: 8},
+	})
+
+	var usernames []string
+	for _, suggestion := range suggestions {
+		usernames = append(usernames, suggestion.Username)
+	}
+	assert.Equal(t, []string{"d", "c", "a", "b", "e"}, usernames)
+}
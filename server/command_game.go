@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/pkg/errors"
+)
+
+type gameOwner struct {
+	Username        string
+	Playtime        int64
+	TwoWeekPlaytime int64
+}
+
+func (p *Plugin) runGameCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	if len(args) == 0 {
+		return nil, true, errors.New("you must provide a game name or app ID")
+	}
+
+	userIDs, err := p.getSteamUserIDs()
+	if err != nil {
+		return nil, false, err
+	}
+	libraries := p.getOwnedGamesForUsers(userIDs, accessLibrary)
+
+	var gameMaps []map[int64]Game
+	for _, gameMap := range libraries {
+		gameMaps = append(gameMaps, gameMap)
+	}
+
+	game, err := ResolveGame(strings.Join(args, " "), gameMaps...)
+	if err != nil {
+		return nil, true, err
+	}
+
+	err = game.PopulateStoreData()
+	if err != nil {
+		p.API.LogError(errors.Wrapf(err, "unable to get store data for app %d", game.AppID).Error())
+	}
+	if game.Name == "" {
+		game.Name = game.StoreData.Name
+	}
+	if game.Name == "" {
+		return nil, true, fmt.Errorf("no game found with app ID %d", game.AppID)
+	}
+
+	var owners []gameOwner
+	for userID, gameMap := range libraries {
+		ownedGame, ok := gameMap[game.AppID]
+		if !ok {
+			continue
+		}
+
+		user, appErr := p.API.GetUser(userID)
+		if appErr != nil {
+			p.API.LogError(errors.Wrapf(appErr, "unable to get user %s", userID).Error())
+			continue
+		}
+
+		owners = append(owners, gameOwner{
+			Username:        user.Username,
+			Playtime:        ownedGame.Playtime,
+			TwoWeekPlaytime: ownedGame.TwoWeekPlaytime,
+		})
+	}
+	sort.Slice(owners, func(i, j int) bool {
+		if owners[i].Playtime == owners[j].Playtime {
+			return owners[i].Username < owners[j].Username
+		}
+		return owners[i].Playtime > owners[j].Playtime
+	})
+
+	output := fmt.Sprintf("#### [%s](%s)\n\n", game.Name, game.StoreLink())
+	output += gameStoreDataSummary(&game.StoreData)
+	output += fmt.Sprintf("\nOwned by %d connected users:\n", len(owners))
+	for _, owner := range owners {
+		output += fmt.Sprintf(" - @%s [%d hours total, %d minutes in the last two weeks]\n", owner.Username, owner.Playtime/60, owner.TwoWeekPlaytime)
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, output), false, nil
+}
+
+// gameStoreDataSummary returns a short markdown summary of storefront details.
+func gameStoreDataSummary(d *GameStoreData) string {
+	var output string
+
+	if genres := d.GenresToString(); genres != "" {
+		output += fmt.Sprintf(" - Genres: %s\n", genres)
+	}
+	if d.Metacritic.Score > 0 {
+		output += fmt.Sprintf(" - Metacritic: [%d](%s)\n", d.Metacritic.Score, d.Metacritic.URL)
+	}
+	if d.Name != "" {
+		output += fmt.Sprintf(" - Price: %s\n", d.PriceToString())
+	}
+
+	return output
+}
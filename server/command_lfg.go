@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/pkg/errors"
+)
+
+func (p *Plugin) runLFGCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	query, slots, note, err := parseLFGArgs(args)
+	if err != nil {
+		return nil, true, err
+	}
+
+	var libraries []map[int64]Game
+	if gameMap, libraryErr := p.getOwnedGamesForUser(extra.UserId, accessSelf); libraryErr == nil {
+		libraries = append(libraries, gameMap)
+	}
+	game, err := ResolveGame(query, libraries...)
+	if err != nil {
+		return nil, true, err
+	}
+	if game.Name == "" {
+		err = game.PopulateStoreData()
+		if err != nil {
+			return nil, false, err
+		}
+		game.Name = game.StoreData.Name
+	}
+	if game.Name == "" {
+		return nil, true, fmt.Errorf("no game found with app ID %d", game.AppID)
+	}
+
+	lfg := &LFG{
+		ID:        model.NewId(),
+		ChannelID: extra.ChannelId,
+		CreatorID: extra.UserId,
+		AppID:     game.AppID,
+		GameName:  game.Name,
+		Slots:     slots,
+		Note:      note,
+		CreatedAt: time.Now().Unix(),
+	}
+
+	post, appErr := p.API.CreatePost(p.makeLFGPost(lfg))
+	if appErr != nil {
+		return nil, false, errors.Wrap(appErr, "unable to post group")
+	}
+	lfg.PostID = post.Id
+
+	_, err = p.storeLFG(lfg, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	err = p.notifyLFG(lfg)
+	if err != nil {
+		p.API.LogError(errors.Wrap(err, "unable to notify users of group").Error())
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Looking for %d players for %s.", slots, game.Name)), false, nil
+}
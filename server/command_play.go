@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// playMaxUsers is the maximum number of users that can be considered for
+	// a play recommendation.
+	playMaxUsers = 25
+
+	// playStoreLookups is the number of top candidate games that will have
+	// their storefront data looked up before final scoring.
+	playStoreLookups = 15
+
+	// playSuggestions is the number of suggestions returned.
+	playSuggestions = 5
+
+	playWeightCoverage    = 50.0
+	playWeightPlaytime    = 15.0
+	playWeightRecent      = 15.0
+	playWeightMultiplayer = 10.0
+	playWeightMetacritic  = 10.0
+)
+
+type playCandidate struct {
+	Game           Game
+	Owners         []string
+	TotalPlaytime  int64
+	RecentPlaytime int64
+	Score          float64
+	Reasons        []string
+}
+
+func (p *Plugin) runPlayCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	userIDs, err := p.getGroupUserIDs(args, extra, playMaxUsers)
+	if err != nil {
+		return nil, true, err
+	}
+	if len(userIDs) < 2 {
+		return nil, true, errors.New("at least two connected Steam users are needed for a recommendation")
+	}
+
+	libraries := p.getOwnedGamesForUsers(userIDs, accessLibrary)
+	if len(libraries) < 2 {
+		return nil, false, errors.New("unable to load enough game libraries for a recommendation")
+	}
+
+	candidates := makePlayCandidates(libraries)
+	if len(candidates) == 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "No shared games were found."), false, nil
+	}
+
+	// Storefront lookups are one request per game, so only the most
+	// promising candidates are looked up.
+	scorePlayCandidates(candidates, len(libraries))
+	if len(candidates) > playStoreLookups {
+		candidates = candidates[:playStoreLookups]
+	}
+	for i := range candidates {
+		err = candidates[i].Game.PopulateStoreData()
+		if err != nil {
+			p.API.LogError(errors.Wrapf(err, "unable to get store data for app %d", candidates[i].Game.AppID).Error())
+		}
+	}
+	scorePlayCandidates(candidates, len(libraries))
+
+	if len(candidates) > playSuggestions {
+		candidates = candidates[:playSuggestions]
+	}
+
+	output := fmt.Sprintf("Suggested games for %d players:\n\n", len(libraries))
+	for i, candidate := range candidates {
+		output += fmt.Sprintf("%d. [%s](%s) [score %.0f]\n", i+1, candidate.Game.Name, candidate.Game.StoreLink(), candidate.Score)
+		output += fmt.Sprintf("   - %s\n", strings.Join(candidate.Reasons, ", "))
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, output), false, nil
+}
+
+// makePlayCandidates returns every game owned by at least two of the
+// provided libraries.
+func makePlayCandidates(libraries map[string]map[int64]Game) []playCandidate {
+	candidateMap := make(map[int64]*playCandidate)
+	for userID, gameMap := range libraries {
+		for appID, game := range gameMap {
+			candidate, ok := candidateMap[appID]
+			if !ok {
+				candidate = &playCandidate{Game: game}
+				candidateMap[appID] = candidate
+			}
+			candidate.Owners = append(candidate.Owners, userID)
+			candidate.TotalPlaytime += game.Playtime
+			candidate.RecentPlaytime += game.TwoWeekPlaytime
+		}
+	}
+
+	var candidates []playCandidate
+	for _, candidate := range candidateMap {
+		if len(candidate.Owners) < 2 {
+			continue
+		}
+		candidates = append(candidates, *candidate)
+	}
+
+	return candidates
+}
+
+// scorePlayCandidates scores and sorts candidates from best to worst. Playtime
+// is scored relative to the most-played candidate.
+func scorePlayCandidates(candidates []playCandidate, players int) {
+	var maxPlaytime, maxRecent int64
+	for _, candidate := range candidates {
+		if candidate.TotalPlaytime > maxPlaytime {
+			maxPlaytime = candidate.TotalPlaytime
+		}
+		if candidate.RecentPlaytime > maxRecent {
+			maxRecent = candidate.RecentPlaytime
+		}
+	}
+
+	for i := range candidates {
+		candidate := &candidates[i]
+		candidate.Score = 0
+		candidate.Reasons = nil
+
+		owners := len(candidate.Owners)
+		candidate.Score += playWeightCoverage * float64(owners) / float64(players)
+		if owners == players {
+			candidate.Reasons = append(candidate.Reasons, "everyone owns it")
+		} else {
+			candidate.Reasons = append(candidate.Reasons, fmt.Sprintf("%d of %d own it", owners, players))
+		}
+
+		if maxPlaytime > 0 && candidate.TotalPlaytime > 0 {
+			candidate.Score += playWeightPlaytime * logRatio(candidate.TotalPlaytime, maxPlaytime)
+			candidate.Reasons = append(candidate.Reasons, fmt.Sprintf("%d hours total across the group", candidate.TotalPlaytime/60))
+		}
+		if maxRecent > 0 && candidate.RecentPlaytime > 0 {
+			candidate.Score += playWeightRecent * logRatio(candidate.RecentPlaytime, maxRecent)
+			candidate.Reasons = append(candidate.Reasons, fmt.Sprintf("%d minutes in the last two weeks", candidate.RecentPlaytime))
+		}
+
+		storeData := candidate.Game.StoreData
+		if storeData.IsMultiplayer() {
+			candidate.Score += playWeightMultiplayer
+			candidate.Reasons = append(candidate.Reasons, "multiplayer")
+		}
+		if storeData.Metacritic.Score > 0 {
+			candidate.Score += playWeightMetacritic * float64(storeData.Metacritic.Score) / 100
+			candidate.Reasons = append(candidate.Reasons, fmt.Sprintf("Metacritic %d", storeData.Metacritic.Score))
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score == candidates[j].Score {
+			return candidates[i].Game.Name < candidates[j].Game.Name
+		}
+		return candidates[i].Score > candidates[j].Score
+	})
+}
+
+// logRatio returns a 0-1 ratio of value to max on a logarithmic scale so a
+// few heavily-played games don't drown out everything else.
+func logRatio(value, max int64) float64 {
+	if value <= 0 || max <= 0 {
+		return 0
+	}
+
+	return math.Log1p(float64(value)) / math.Log1p(float64(max))
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakePlayCandidates(t *testing.T) {
+	libraries := map[string]map[int64]Game{
+		"user1": {
+			1: {AppID: 1, Name: "Shared", Playtime: 100, TwoWeekPlaytime: 10},
+			2: {AppID: 2, Name: "Solo"},
+		},
+		"user2": {
+			1: {AppID: 1, Name: "Shared", Playtime: 50, TwoWeekPlaytime: 5},
+		},
+	}
+
+	candidates := makePlayCandidates(libraries)
+	require.Len(t, candidates, 1)
+	assert.Equal(t, int64(1), candidates[0].Game.AppID)
+	assert.Len(t, candidates[0].Owners, 2)
+	assert.Equal(t, int64(150), candidates[0].TotalPlaytime)
+	assert.Equal(t, int64(15), candidates[0].RecentPlaytime)
+}
+
+func TestScorePlayCandidates(t *testing.T) {
+	multiplayer := GameStoreData{Categories: []GameCategories{{ID: gameCategoryOnlineCoop}}}
+
+	t.Run("coverage wins", func(t *testing.T) {
+		candidates := []playCandidate{
+			{Game: Game{AppID: 1, Name: "Some"}, Owners: []string{"a", "b"}},
+			{Game: Game{AppID: 2, Name: "All"}, Owners: []string{"a", "b", "c"}},
+		}
+		scorePlayCandidates(candidates, 3)
+		assert.Equal(t, int64(2), candidates[0].Game.AppID)
+		assert.Contains(t, candidates[0].Reasons, "everyone owns it")
+		assert.Contains(t, candidates[1].Reasons, "2 of 3 own it")
+	})
+
+	t.Run("multiplayer and metacritic break ties", func(t *testing.T) {
+		candidates := []playCandidate{
+			{Game: Game{AppID: 1, Name: "Plain"}, Owners: []string{"a", "b"}},
+			{Game: Game{AppID: 2, Name: "Rated", StoreData: GameStoreData{Metacritic: GameMetacritic{Score: 90}}}, Owners: []string{"a", "b"}},
+			{Game: Game{AppID: 3, Name: "Coop", StoreData: multiplayer}, Owners: []string{"a", "b"}},
+		}
+		scorePlayCandidates(candidates, 2)
+		assert.Equal(t, int64(3), candidates[0].Game.AppID)
+		assert.Equal(t, int64(2), candidates[1].Game.AppID)
+		assert.Equal(t, int64(1), candidates[2].Game.AppID)
+	})
+
+	t.Run("recent playtime", func(t *testing.T) {
+		candidates := []playCandidate{
+			{Game: Game{AppID: 1, Name: "Old"}, Owners: []string{"a", "b"}, TotalPlaytime: 1000},
+			{Game: Game{AppID: 2, Name: "New"}, Owners: []string{"a", "b"}, TotalPlaytime: 1000, RecentPlaytime: 120},
+		}
+		scorePlayCandidates(candidates, 2)
+		assert.Equal(t, int64(2), candidates[0].Game.AppID)
+	})
+}
@@ -1,61 +1,371 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/pkg/errors"
 )
 
+// Scopes of the users included in /steam recent.
+const (
+	recentScopeServer  = "server"
+	recentScopeTeam    = "team"
+	recentScopeChannel = "channel"
+)
+
+// recentPageSize is the page size used when listing team and channel members.
+const recentPageSize = 200
+
+// How /steam recent breaks down playtime.
+const (
+	// recentBreakdownNone shows only game totals.
+	recentBreakdownNone = iota
+
+	// recentBreakdownPlayers lists the players of each game.
+	recentBreakdownPlayers
+
+	// recentBreakdownUsers shows each player's total and top game instead of
+	// game totals.
+	recentBreakdownUsers
+)
+
 type recentGame struct {
 	AppID    int64
 	Playtime int64
 }
 
+// recentPlayer is a player's playtime in a recent summary.
+type recentPlayer struct {
+	UserID   string
+	Playtime int64
+}
+
+// parseRecentBreakdown removes a breakdown flag from the arguments.
+func parseRecentBreakdown(args []string) (int, []string, error) {
+	breakdown := recentBreakdownNone
+	var remaining []string
+	for _, arg := range args {
+		var flagBreakdown int
+		switch arg {
+		case "--players":
+			flagBreakdown = recentBreakdownPlayers
+		case "--by-user":
+			flagBreakdown = recentBreakdownUsers
+		default:
+			remaining = append(remaining, arg)
+			continue
+		}
+
+		if breakdown != recentBreakdownNone && breakdown != flagBreakdown {
+			return 0, nil, errors.New("only one of --players or --by-user can be provided")
+		}
+		breakdown = flagBreakdown
+	}
+
+	return breakdown, remaining, nil
+}
+
+// sortRecentPlayers returns players sorted by playtime, most played first.
+func sortRecentPlayers(playtime map[string]int64) []recentPlayer {
+	var players []recentPlayer
+	for userID, minutes := range playtime {
+		players = append(players, recentPlayer{UserID: userID, Playtime: minutes})
+	}
+	sort.Slice(players, func(i, j int) bool {
+		if players[i].Playtime != players[j].Playtime {
+			return players[i].Playtime > players[j].Playtime
+		}
+		return players[i].UserID < players[j].UserID
+	})
+
+	return players
+}
+
+// formatRecentSummary formats the games of a recent summary with the
+// requested breakdown. userPlaytime holds the minutes each user played per
+// game.
+func (p *Plugin) formatRecentSummary(userPlaytime map[string]map[int64]int64, gameNames map[int64]string, breakdown int, gameSuffix func(recentGame) string) string {
+	if breakdown == recentBreakdownUsers {
+		totals := make(map[string]int64)
+		for userID, games := range userPlaytime {
+			for _, minutes := range games {
+				totals[userID] += minutes
+			}
+		}
+
+		var output string
+		for _, player := range sortRecentPlayers(totals) {
+			if player.Playtime == 0 {
+				continue
+			}
+			top := sortRecentGames(userPlaytime[player.UserID])[0]
+			game := Game{AppID: top.AppID, Name: gameNames[top.AppID]}
+			output += fmt.Sprintf(" - %s [%d minutes], mostly [%s](%s) [%d minutes]\n", p.getUserMention(player.UserID), player.Playtime, game.Name, game.StoreLink(), top.Playtime)
+		}
+		return output
+	}
+
+	gamesPlayed := make(map[int64]int64)
+	for _, games := range userPlaytime {
+		for appID, minutes := range games {
+			gamesPlayed[appID] += minutes
+		}
+	}
+
+	mentions := make(map[string]string)
+	var output string
+	for _, recentGame := range sortRecentGames(gamesPlayed) {
+		game := Game{AppID: recentGame.AppID, Name: gameNames[recentGame.AppID]}
+		output += fmt.Sprintf(" - [%s](%s) [%d minutes]%s\n", game.Name, game.StoreLink(), recentGame.Playtime, gameSuffix(recentGame))
+		if breakdown != recentBreakdownPlayers {
+			continue
+		}
+
+		players := make(map[string]int64)
+		for userID, games := range userPlaytime {
+			if minutes := games[recentGame.AppID]; minutes > 0 {
+				players[userID] = minutes
+			}
+		}
+		for _, player := range sortRecentPlayers(players) {
+			if _, ok := mentions[player.UserID]; !ok {
+				mentions[player.UserID] = p.getUserMention(player.UserID)
+			}
+			output += fmt.Sprintf("   - %s [%d minutes]\n", mentions[player.UserID], player.Playtime)
+		}
+	}
+
+	return output
+}
+
+// parseRecentScope removes a scope flag from the arguments, returning the
+// scope. The default scope is used when no flag is given.
+func parseRecentScope(args []string, defaultScope string) (string, []string, error) {
+	scope := ""
+	var remaining []string
+	for _, arg := range args {
+		var flagScope string
+		switch arg {
+		case "--server":
+			flagScope = recentScopeServer
+		case "--team":
+			flagScope = recentScopeTeam
+		case "--channel":
+			flagScope = recentScopeChannel
+		default:
+			remaining = append(remaining, arg)
+			continue
+		}
+
+		if scope != "" && scope != flagScope {
+			return "", nil, errors.New("only one of --server, --team or --channel can be provided")
+		}
+		scope = flagScope
+	}
+	if scope == "" {
+		scope = defaultScope
+	}
+
+	return scope, remaining, nil
+}
+
+// recentScopeDescription returns how a scope is described in summaries.
+func recentScopeDescription(scope string) string {
+	switch scope {
+	case recentScopeTeam:
+		return " in this team"
+	case recentScopeChannel:
+		return " in this channel"
+	}
+
+	return ""
+}
+
+// getRecentScopeUserIDs returns the users who have not opted out of stats and
+// are in the scope.
+func (p *Plugin) getRecentScopeUserIDs(scope string, extra *model.CommandArgs) ([]string, error) {
+	userIDs, err := p.getSteamUserIDsForAccess(accessStats)
+	if err != nil {
+		return nil, err
+	}
+
+	var getPage func(page int) ([]*model.User, *model.AppError)
+	switch scope {
+	case recentScopeServer:
+		return userIDs, nil
+	case recentScopeTeam:
+		getPage = func(page int) ([]*model.User, *model.AppError) {
+			return p.API.GetUsersInTeam(extra.TeamId, page, recentPageSize)
+		}
+	case recentScopeChannel:
+		getPage = func(page int) ([]*model.User, *model.AppError) {
+			return p.API.GetUsersInChannel(extra.ChannelId, "username", page, recentPageSize)
+		}
+	default:
+		return nil, fmt.Errorf("%s is not a valid scope", scope)
+	}
+
+	members := make(map[string]bool)
+	for page := 0; ; page++ {
+		users, appErr := getPage(page)
+		if appErr != nil {
+			return nil, errors.Wrapf(appErr, "unable to get %s members", scope)
+		}
+		for _, user := range users {
+			members[user.Id] = true
+		}
+		if len(users) < recentPageSize {
+			break
+		}
+	}
+
+	var scopedUserIDs []string
+	for _, userID := range userIDs {
+		if members[userID] {
+			scopedUserIDs = append(scopedUserIDs, userID)
+		}
+	}
+
+	return scopedUserIDs, nil
+}
+
 func (p *Plugin) runListRecentGamesCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
-	keys, appErr := p.API.KVList(0, 1000)
-	if appErr != nil {
-		return nil, false, appErr
+	scope, args, err := parseRecentScope(args, p.getConfiguration().getRecentDefaultScope())
+	if err != nil {
+		return nil, true, err
+	}
+	breakdown, args, err := parseRecentBreakdown(args)
+	if err != nil {
+		return nil, true, err
+	}
+	window, args, err := parsePlaytimeWindow(args, time.Now())
+	if err != nil {
+		return nil, true, err
+	}
+	if len(args) > 0 {
+		return nil, true, fmt.Errorf("unknown arguments %s", strings.Join(args, " "))
+	}
+
+	userIDs, err := p.getRecentScopeUserIDs(scope, extra)
+	if err != nil {
+		return nil, false, err
+	}
+	if window != nil {
+		return p.runListPlaytimeWindowCommand(*window, userIDs, recentScopeDescription(scope), breakdown)
 	}
-	keys = removeNonPlayerKVKeys(keys)
 
 	var totalPlaytime int64
-	gamesPlayed := make(map[int64]int64)
-	gamesReference := make(map[int64]Game)
+	userPlaytime := make(map[string]map[int64]int64)
+	gameNames := make(map[int64]string)
 
-	for _, key := range keys {
-		result, err := p.makeSteamAPICall(key, steamAPIRecentlyPlayedGames)
+	for _, userID := range userIDs {
+		games, err := p.getRecentlyPlayedGames(userID, accessStats)
 		if err != nil {
-			p.API.LogError(errors.Wrapf(err, "unable to get recently-played games for %s", key).Error())
+			p.API.LogError(errors.Wrapf(err, "unable to get recently-played games for %s", userID).Error())
 			continue
 		}
 
-		var gameListResponse GamesListResponse
-		err = json.Unmarshal(result, &gameListResponse)
+		userPlaytime[userID] = make(map[int64]int64)
+		for _, game := range games {
+			totalPlaytime += game.TwoWeekPlaytime
+			userPlaytime[userID][game.AppID] += game.TwoWeekPlaytime
+			gameNames[game.AppID] = game.Name
+		}
+	}
+
+	output := fmt.Sprintf("Recently Played Summary for %d Players%s [%d minutes total]:\n\n", len(userIDs), recentScopeDescription(scope), totalPlaytime)
+	output += p.formatRecentSummary(userPlaytime, gameNames, breakdown, func(recentGame) string { return "" })
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, output), false, nil
+}
+
+// runListPlaytimeWindowCommand summarizes the playtime of users over a window
+// using stored playtime snapshots, marking games as trending versus the
+// previous window.
+func (p *Plugin) runListPlaytimeWindowCommand(window playtimeWindow, userIDs []string, scopeDescription string, breakdown int) (*model.CommandResponse, bool, error) {
+	var players, missingHistory int
+	var hasPreviousHistory bool
+	var totalPlaytime int64
+	userPlaytime := make(map[string]map[int64]int64)
+	previousGamesPlayed := make(map[int64]int64)
+	gameNames := make(map[int64]string)
+
+	for _, userID := range userIDs {
+		delta, ok, err := p.getPlaytimeDelta(userID, window, accessStats)
 		if err != nil {
-			return nil, false, err
+			p.API.LogError(errors.Wrapf(err, "unable to get playtime history for %s", userID).Error())
+			continue
+		}
+		if !ok {
+			missingHistory++
+			continue
 		}
+		players++
 
-		for _, game := range gameListResponse.Response.Games {
-			totalPlaytime += game.TwoWeekPlaytime
-			gamesPlayed[game.AppID] += game.TwoWeekPlaytime
-			gamesReference[game.AppID] = game
+		userPlaytime[userID] = delta
+		for _, minutes := range delta {
+			totalPlaytime += minutes
+		}
+
+		previousDelta, ok, err := p.getPlaytimeDelta(userID, window.Previous(), accessStats)
+		if err != nil {
+			p.API.LogError(errors.Wrapf(err, "unable to get playtime history for %s", userID).Error())
+		}
+		if ok {
+			hasPreviousHistory = true
+			for appID, minutes := range previousDelta {
+				previousGamesPlayed[appID] += minutes
+			}
+		}
+
+		snapshot, err := p.getPlaytimeSnapshotOnOrBefore(userID, window.End)
+		if err == nil && snapshot != nil {
+			for appID, game := range snapshot.Games {
+				gameNames[appID] = game.Name
+			}
+		}
+	}
+
+	output := fmt.Sprintf("Played Summary from %s to %s for %d Players%s [%d minutes total]:\n\n",
+		window.Start.Format(playtimeSnapshotDateFormat), window.End.Format(playtimeSnapshotDateFormat), players, scopeDescription, totalPlaytime)
+	output += p.formatRecentSummary(userPlaytime, gameNames, breakdown, func(game recentGame) string {
+		if !hasPreviousHistory {
+			return ""
 		}
+		return playtimeTrend(game.Playtime, previousGamesPlayed[game.AppID])
+	})
+	if missingHistory > 0 {
+		output += fmt.Sprintf("\n%d players don't have enough playtime history for this period yet.\n", missingHistory)
 	}
 
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, output), false, nil
+}
+
+func sortRecentGames(gamesPlayed map[int64]int64) []recentGame {
 	var gamesPlayedSlice []recentGame
 	for k, v := range gamesPlayed {
 		gamesPlayedSlice = append(gamesPlayedSlice, recentGame{AppID: k, Playtime: v})
 	}
 	sort.Slice(gamesPlayedSlice, func(i, j int) bool { return gamesPlayedSlice[i].Playtime > gamesPlayedSlice[j].Playtime })
 
-	output := fmt.Sprintf("Recently Played Summary for %d Players [%d minutes total]:\n\n", len(keys), totalPlaytime)
-	for _, recentGame := range gamesPlayedSlice {
-		game := gamesReference[recentGame.AppID]
-		output += fmt.Sprintf(" - [%s](%s) [%d minutes]\n", game.Name, game.StoreLink(), recentGame.Playtime)
+	return gamesPlayedSlice
+}
+
+// playtimeTrend returns a trend marker comparing playtime with the previous
+// period.
+func playtimeTrend(current, previous int64) string {
+	switch {
+	case previous == 0:
+		return " :new:"
+	case current > previous:
+		return " :arrow_up:"
+	case current < previous:
+		return " :arrow_down:"
 	}
 
-	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, output), false, nil
+	return ""
 }
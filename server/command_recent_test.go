@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRecentScope(t *testing.T) {
+	scope, remaining, err := parseRecentScope([]string{"--since", "30d"}, recentScopeServer)
+	require.NoError(t, err)
+	assert.Equal(t, recentScopeServer, scope)
+	assert.Equal(t, []string{"--since", "30d"}, remaining)
+
+	scope, remaining, err = parseRecentScope([]string{"--channel"}, recentScopeTeam)
+	require.NoError(t, err)
+	assert.Equal(t, recentScopeChannel, scope)
+	assert.Empty(t, remaining)
+
+	_, remaining, err = parseRecentScope([]string{"--group", "raiders"}, recentScopeServer)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--group", "raiders"}, remaining)
+
+	_, _, err = parseRecentScope([]string{"--team", "--channel"}, recentScopeServer)
+	assert.Error(t, err)
+}
+
+func TestParseRecentBreakdown(t *testing.T) {
+	breakdown, remaining, err := parseRecentBreakdown([]string{"--team"})
+	require.NoError(t, err)
+	assert.Equal(t, recentBreakdownNone, breakdown)
+	assert.Equal(t, []string{"--team"}, remaining)
+
+	breakdown, remaining, err = parseRecentBreakdown([]string{"--players", "--since", "4w"})
+	require.NoError(t, err)
+	assert.Equal(t, recentBreakdownPlayers, breakdown)
+	assert.Equal(t, []string{"--since", "4w"}, remaining)
+
+	breakdown, _, err = parseRecentBreakdown([]string{"--by-user"})
+	require.NoError(t, err)
+	assert.Equal(t, recentBreakdownUsers, breakdown)
+
+	_, _, err = parseRecentBreakdown([]string{"--players", "--by-user"})
+	assert.Error(t, err)
+}
+
+func TestSortRecentPlayers(t *testing.T) {
+	players := sortRecentPlayers(map[string]int64{"user1": 30, "user2": 120, "user3": 30})
+
+	assert.Equal(t, []recentPlayer{
+		{UserID: "user2", Playtime: 120},
+		{UserID: "user1", Playtime: 30},
+		{UserID: "user3", Playtime: 30},
+	}, players)
+}
@@ -2,50 +2,146 @@ package main
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/pkg/errors"
 )
 
 func (p *Plugin) runSettingsCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	userInfo, err := p.getSteamUserInfoByID(extra.UserId)
+	if err != nil {
+		return nil, true, err
+	}
+
 	if len(args) == 0 {
-		return nil, true, errors.New("must provide a setting")
+		return p.runListSettingsCommand(userInfo)
+	}
+	switch args[0] {
+	case "edit":
+		err = p.openSettingsDialog(userInfo.Settings, extra)
+		if err != nil {
+			return nil, false, err
+		}
+		return &model.CommandResponse{}, false, nil
+	case "hide-game":
+		return p.runHideGameCommand(args[1:], extra, true)
+	case "show-game":
+		return p.runHideGameCommand(args[1:], extra, false)
+	}
+
+	setting := getUserSetting(args[0])
+	if setting == nil {
+		return nil, true, fmt.Errorf("%s is not a valid setting, must be %s", args[0], getUserSettingNames())
 	}
 	if len(args) == 1 {
 		return nil, true, errors.New("must provide setting value")
 	}
-	setting := args[0]
 	value := args[1]
 
-	switch setting {
-	case "show-profile":
-		var shown bool
-
-		switch value {
-		case "true":
-			shown = true
-		case "false":
-			shown = false
-		default:
-			return nil, true, fmt.Errorf("%s is not a valid 'show-profile' setting, must be 'true' or 'false'", value)
-		}
+	err = setting.Validate(value)
+	if err != nil {
+		return nil, true, err
+	}
+
+	if setting.get(userInfo.Settings) == value {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Setting %s is already %s", setting.Name, value)), false, nil
+	}
+	setting.set(userInfo.Settings, value)
+
+	err = p.storeSteamUser(userInfo)
+	if err != nil {
+		return nil, true, err
+	}
+	p.recordAudit(extra.UserId, extra.UserId, AuditActionSettingsChange, map[string]string{setting.Name: value})
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Setting %s updated to %s", setting.Name, value)), false, nil
+}
 
-		userInfo, err := p.getSteamUserInfoByID(extra.UserId)
+func (p *Plugin) runListSettingsCommand(userInfo *SteamUserInfo) (*model.CommandResponse, bool, error) {
+	output := "Your settings:\n"
+	for _, setting := range userSettings {
+		output += fmt.Sprintf(" - `%s`: %s - %s\n", setting.Name, setting.get(userInfo.Settings), setting.Description)
+	}
+
+	if len(userInfo.Settings.HiddenGames) > 0 {
+		// Names come from the user's own library when it can be read.
+		gameMap, err := p.getOwnedGamesForUser(userInfo.MattermostUserID, accessSelf)
 		if err != nil {
-			return nil, true, err
+			p.API.LogError(errors.Wrapf(err, "unable to get owned games for %s", userInfo.MattermostUserID).Error())
 		}
-		if userInfo.Settings.ShowProfile == shown {
-			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Setting %s is already %s", setting, value)), false, nil
+
+		var hiddenGames []string
+		for _, appID := range userInfo.Settings.HiddenGames {
+			game, ok := gameMap[appID]
+			if !ok {
+				game = Game{AppID: appID, Name: fmt.Sprintf("app %d", appID)}
+			}
+			hiddenGames = append(hiddenGames, fmt.Sprintf("[%s](%s)", game.Name, game.StoreLink()))
 		}
-		userInfo.Settings.ShowProfile = shown
-		err = p.storeSteamUser(userInfo)
-		if err != nil {
-			return nil, true, err
+		output += fmt.Sprintf("\nHidden games: %s\n", strings.Join(hiddenGames, ", "))
+	}
+
+	output += "\nUse `/steam settings [setting] [value]` to change a setting or `/steam settings edit` to edit them all at once."
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, output), false, nil
+}
+
+// runHideGameCommand adds a game to, or removes it from, the user's hidden
+// games. Hidden games are left out of everything shown to other users.
+func (p *Plugin) runHideGameCommand(args []string, extra *model.CommandArgs, hide bool) (*model.CommandResponse, bool, error) {
+	if len(args) == 0 {
+		return nil, true, errors.New("you must provide a game name or app ID")
+	}
+
+	userInfo, err := p.getSteamUserInfoByID(extra.UserId)
+	if err != nil {
+		return nil, true, err
+	}
+
+	gameMap, err := p.getOwnedGamesForUser(extra.UserId, accessSelf)
+	if err != nil {
+		return nil, false, err
+	}
+	game, err := ResolveGame(strings.Join(args, " "), gameMap)
+	if err != nil {
+		return nil, true, err
+	}
+	if game.Name == "" {
+		game.Name = fmt.Sprintf("app %d", game.AppID)
+	}
+
+	if userInfo.Settings.IsGameHidden(game.AppID) == hide {
+		if hide {
+			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("%s is already hidden", game.Name)), false, nil
+		}
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("%s is not hidden", game.Name)), false, nil
+	}
+
+	var hiddenGames []int64
+	for _, appID := range userInfo.Settings.HiddenGames {
+		if appID != game.AppID {
+			hiddenGames = append(hiddenGames, appID)
 		}
+	}
+	if hide {
+		hiddenGames = append(hiddenGames, game.AppID)
+	}
+	userInfo.Settings.HiddenGames = hiddenGames
 
-	default:
-		return nil, true, fmt.Errorf("%s is not a valid setting, must be 'show-profile'", value)
+	err = p.storeSteamUser(userInfo)
+	if err != nil {
+		return nil, true, err
 	}
+	hiddenSetting := "show-game"
+	if hide {
+		hiddenSetting = "hide-game"
+	}
+	p.recordAudit(extra.UserId, extra.UserId, AuditActionSettingsChange, map[string]string{hiddenSetting: strconv.FormatInt(game.AppID, 10)})
 
-	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Setting %s updated to %s", setting, value)), false, nil
+	if hide {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("%s is now hidden from other users", game.Name)), false, nil
+	}
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("%s is no longer hidden from other users", game.Name)), false, nil
 }
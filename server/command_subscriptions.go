@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/pkg/errors"
+)
+
+const subscriptionsMessage = `Usage:
+ - |/steam subscribe news [game] [--feed name]| - Post news for a game in this channel, optionally only from the given feeds such as "steam_community_announcements" or "patchnotes"
+ - |/steam subscriptions| - List the news subscriptions in this channel
+ - |/steam subscriptions remove [game]| - Remove a news subscription from this channel
+`
+
+func getSubscriptionsMessage() string {
+	return strings.Replace(subscriptionsMessage, "|", "`", -1)
+}
+
+func (p *Plugin) runSubscribeCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	if len(args) == 0 || args[0] != "news" {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getSubscriptionsMessage()), false, nil
+	}
+
+	userError, err := p.checkCanManageSubscriptions(extra)
+	if err != nil {
+		return nil, userError, err
+	}
+
+	subscription := &NewsSubscription{CreatorID: extra.UserId}
+
+	var gameArgs []string
+	args = args[1:]
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--feed" {
+			if i+1 >= len(args) {
+				return nil, true, errors.New("--feed requires a feed name")
+			}
+			subscription.Feeds = append(subscription.Feeds, args[i+1])
+			i++
+			continue
+		}
+		gameArgs = append(gameArgs, args[i])
+	}
+	if len(gameArgs) == 0 {
+		return nil, true, errors.New("you must provide a game name or app ID")
+	}
+
+	// Prefer the user's own library when resolving the game, but allow
+	// subscribing without a connected account.
+	var libraries []map[int64]Game
+	if gameMap, err := p.getOwnedGamesForUser(extra.UserId, accessSelf); err == nil {
+		libraries = append(libraries, gameMap)
+	}
+
+	game, err := ResolveGame(strings.Join(gameArgs, " "), libraries...)
+	if err != nil {
+		return nil, true, err
+	}
+	if game.Name == "" {
+		err = game.PopulateStoreData()
+		if err != nil {
+			return nil, false, err
+		}
+		game.Name = game.StoreData.Name
+	}
+	if game.Name == "" {
+		return nil, true, fmt.Errorf("no game found with app ID %d", game.AppID)
+	}
+	subscription.AppID = game.AppID
+	subscription.Name = game.Name
+
+	// Existing news is marked as seen so that only news published after
+	// subscribing is posted.
+	items, err := getGameNews(game.AppID)
+	if err != nil {
+		return nil, false, err
+	}
+	subscription.NewItems(items)
+
+	subscriptions, err := p.getNewsSubscriptions(extra.ChannelId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Replace any existing subscription for the same game.
+	var updated []*NewsSubscription
+	for _, existing := range subscriptions {
+		if existing.AppID != subscription.AppID {
+			updated = append(updated, existing)
+		}
+	}
+	updated = append(updated, subscription)
+
+	err = p.storeNewsSubscriptions(extra.ChannelId, updated)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("This channel is now subscribed to news for %s.", subscription.Description())), false, nil
+}
+
+// checkCanManageSubscriptions returns an error if the user can't change the
+// news subscriptions of the channel the command was run in. True is returned
+// along with the error if it's the user's error.
+func (p *Plugin) checkCanManageSubscriptions(extra *model.CommandArgs) (bool, error) {
+	channel, appErr := p.API.GetChannel(extra.ChannelId)
+	if appErr != nil {
+		return false, errors.Wrap(appErr, "unable to get channel")
+	}
+	if !p.canManageChannel(extra.UserId, channel) {
+		return true, errors.New("you don't have permission to manage this channel")
+	}
+
+	return false, nil
+}
+
+func (p *Plugin) runSubscriptionsCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	if len(args) > 0 {
+		if args[0] == "remove" {
+			return p.runRemoveSubscriptionCommand(args[1:], extra)
+		}
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getSubscriptionsMessage()), false, nil
+	}
+
+	subscriptions, err := p.getNewsSubscriptions(extra.ChannelId)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(subscriptions) == 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "This channel has no news subscriptions.\n\n"+getSubscriptionsMessage()), false, nil
+	}
+
+	output := "News subscriptions in this channel:\n"
+	for _, subscription := range subscriptions {
+		output += fmt.Sprintf(" - %s\n", subscription.Description())
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, output), false, nil
+}
+
+func (p *Plugin) runRemoveSubscriptionCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	if len(args) == 0 {
+		return nil, true, errors.New("you must provide a game name or app ID")
+	}
+
+	userError, err := p.checkCanManageSubscriptions(extra)
+	if err != nil {
+		return nil, userError, err
+	}
+
+	subscriptions, err := p.getNewsSubscriptions(extra.ChannelId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	subscribedGames := make(map[int64]Game)
+	for _, subscription := range subscriptions {
+		subscribedGames[subscription.AppID] = Game{AppID: subscription.AppID, Name: subscription.Name}
+	}
+
+	game := FindGameInLibraries(strings.Join(args, " "), subscribedGames)
+	if game == nil {
+		return nil, true, fmt.Errorf("this channel has no news subscription for %s", strings.Join(args, " "))
+	}
+
+	var updated []*NewsSubscription
+	for _, subscription := range subscriptions {
+		if subscription.AppID != game.AppID {
+			updated = append(updated, subscription)
+		}
+	}
+
+	err = p.storeNewsSubscriptions(extra.ChannelId, updated)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("This channel is no longer subscribed to news for %s.", game.Name)), false, nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	leaderboardFrequencyNever  = "never"
+	leaderboardFrequencyDaily  = "daily"
+	leaderboardFrequencyWeekly = "weekly"
+
+	// leaderboardSize is the number of ranks shown on a leaderboard. Users
+	// tied for the last rank are all shown.
+	leaderboardSize = 10
+)
+
+type leaderboardEntry struct {
+	UserID   string
+	Username string
+	Minutes  int64
+	Rank     int
+}
+
+func (p *Plugin) runTopCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	var recent bool
+	var gameArgs []string
+	for _, arg := range args {
+		if arg == "--recent" {
+			recent = true
+			continue
+		}
+		gameArgs = append(gameArgs, arg)
+	}
+
+	libraries, err := p.getLeaderboardLibraries()
+	if err != nil {
+		return nil, false, err
+	}
+
+	var game *Game
+	if len(gameArgs) > 0 {
+		var gameMaps []map[int64]Game
+		for _, gameMap := range libraries {
+			gameMaps = append(gameMaps, gameMap)
+		}
+
+		game, err = ResolveGame(strings.Join(gameArgs, " "), gameMaps...)
+		if err != nil {
+			return nil, true, err
+		}
+	}
+
+	output := p.makeLeaderboard(libraries, game, recent)
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, output), false, nil
+}
+
+// getLeaderboardLibraries returns the owned games of every connected user who
+// has not opted out of stats.
+func (p *Plugin) getLeaderboardLibraries() (map[string]map[int64]Game, error) {
+	userIDs, err := p.getSteamUserIDsForAccess(accessStats)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.getOwnedGamesForUsers(userIDs, accessStats), nil
+}
+
+// makeLeaderboard returns a leaderboard of total or two-week playtime for a
+// single game, or for all games when game is nil.
+func (p *Plugin) makeLeaderboard(libraries map[string]map[int64]Game, game *Game, recent bool) string {
+	var entries []leaderboardEntry
+	for userID, gameMap := range libraries {
+		var minutes int64
+		for appID, ownedGame := range gameMap {
+			if game != nil && appID != game.AppID {
+				continue
+			}
+			if recent {
+				minutes += ownedGame.TwoWeekPlaytime
+			} else {
+				minutes += ownedGame.Playtime
+			}
+			if game != nil && game.Name == "" {
+				game.Name = ownedGame.Name
+			}
+		}
+		if minutes == 0 {
+			continue
+		}
+
+		user, appErr := p.API.GetUser(userID)
+		if appErr != nil {
+			p.API.LogError(errors.Wrapf(appErr, "unable to get user %s", userID).Error())
+			continue
+		}
+
+		entries = append(entries, leaderboardEntry{
+			UserID:   userID,
+			Username: user.Username,
+			Minutes:  minutes,
+		})
+	}
+	entries = rankLeaderboard(entries, leaderboardSize)
+
+	period := "all time"
+	if recent {
+		period = "the last two weeks"
+	}
+
+	var output string
+	if game != nil {
+		output = fmt.Sprintf("#### Top players of [%s](%s) for %s\n\n", game.Name, game.StoreLink(), period)
+	} else {
+		output = fmt.Sprintf("#### Most hours played overall for %s\n\n", period)
+	}
+	if len(entries) == 0 {
+		return output + "No playtime has been recorded yet.\n"
+	}
+
+	for _, entry := range entries {
+		output += fmt.Sprintf("%d. @%s [%s]\n", entry.Rank, entry.Username, formatPlaytime(entry.Minutes))
+	}
+
+	return output
+}
+
+// rankLeaderboard sorts entries by minutes played and assigns competition
+// ranks so tied users share a rank. Only entries ranked within size are
+// returned.
+func rankLeaderboard(entries []leaderboardEntry, size int) []leaderboardEntry {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Minutes == entries[j].Minutes {
+			return entries[i].Username < entries[j].Username
+		}
+		return entries[i].Minutes > entries[j].Minutes
+	})
+
+	var ranked []leaderboardEntry
+	for i, entry := range entries {
+		entry.Rank = i + 1
+		if i > 0 && entries[i-1].Minutes == entry.Minutes {
+			entry.Rank = ranked[i-1].Rank
+		}
+		if entry.Rank > size {
+			break
+		}
+		ranked = append(ranked, entry)
+	}
+
+	return ranked
+}
+
+// formatPlaytime returns a human readable playtime.
+func formatPlaytime(minutes int64) string {
+	if minutes < 60 {
+		return fmt.Sprintf("%d minutes", minutes)
+	}
+
+	return fmt.Sprintf("%d hours %d minutes", minutes/60, minutes%60)
+}
+
+func leaderboardJobInterval(config *configuration) time.Duration {
+	if !config.SteamSummaryEnable {
+		return 0
+	}
+
+	switch config.LeaderboardFrequency {
+	case leaderboardFrequencyDaily:
+		return 24 * time.Hour
+	case leaderboardFrequencyWeekly:
+		return 7 * 24 * time.Hour
+	}
+
+	return 0
+}
+
+// runLeaderboardJob posts the server-wide leaderboards to the summary channel.
+func (p *Plugin) runLeaderboardJob() error {
+	config := p.getConfiguration()
+
+	libraries, err := p.getLeaderboardLibraries()
+	if err != nil {
+		return err
+	}
+
+	recentBoard := p.makeLeaderboard(libraries, nil, true)
+	overallBoard := p.makeLeaderboard(libraries, nil, false)
+
+	return p.PostToChannelByIDAsBot(config.SteamSummaryChannelID, recentBoard+"\n"+overallBoard)
+}
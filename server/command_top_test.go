@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRankLeaderboard(t *testing.T) {
+	entries := []leaderboardEntry{
+		{Username: "d", Minutes: 10},
+		{Username: "b", Minutes: 50},
+		{Username: "a", Minutes: 50},
+		{Username: "c", Minutes: 20},
+		{Username: "e", Minutes: 10},
+	}
+
+	t.Run("ties share a rank", func(t *testing.T) {
+		ranked := rankLeaderboard(append([]leaderboardEntry{}, entries...), 10)
+		var usernames []string
+		var ranks []int
+		for _, entry := range ranked {
+			usernames = append(usernames, entry.Username)
+			ranks = append(ranks, entry.Rank)
+		}
+		assert.Equal(t, []string{"a", "b", "c", "d", "e"}, usernames)
+		assert.Equal(t, []int{1, 1, 3, 4, 4}, ranks)
+	})
+
+	t.Run("ties at the cutoff are kept", func(t *testing.T) {
+		ranked := rankLeaderboard(append([]leaderboardEntry{}, entries...), 1)
+		assert.Len(t, ranked, 2)
+	})
+
+	t.Run("size limit", func(t *testing.T) {
+		ranked := rankLeaderboard(append([]leaderboardEntry{}, entries...), 3)
+		assert.Len(t, ranked, 3)
+	})
+}
+
+func TestFormatPlaytime(t *testing.T) {
+	assert.Equal(t, "45 minutes", formatPlaytime(45))
+	assert.Equal(t, "2 hours 5 minutes", formatPlaytime(125))
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// wishlistMaxUsers is the maximum number of users whose wishlists can be
+	// compared.
+	wishlistMaxUsers = 25
+
+	// wishlistSuggestions is the number of wishlisted games shown.
+	wishlistSuggestions = 10
+)
+
+// WishlistResponse is an API response for a player's wishlist.
+type WishlistResponse struct {
+	Response Wishlist `json:"response"`
+}
+
+// Wishlist is a player's Steam wishlist.
+type Wishlist struct {
+	Items []WishlistItem `json:"items"`
+}
+
+// WishlistItem is a game on a player's wishlist.
+type WishlistItem struct {
+	AppID     int64 `json:"appid"`
+	Priority  int   `json:"priority"`
+	DateAdded int64 `json:"date_added"`
+}
+
+type wishlistOverlap struct {
+	Game        Game
+	Wishlisters int
+	Owners      int
+}
+
+// getWishlistForUser returns the wishlist of a user if their privacy settings
+// allow it to be read for the purpose. Hidden games are left out for others.
+func (p *Plugin) getWishlistForUser(userID string, access steamDataAccess) ([]WishlistItem, error) {
+	userInfo, err := p.getSteamUserForAccess(userID, access)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := p.makeSteamAPICall(userID+SteamUserKey, steamAPIGetWishlist)
+	if err != nil {
+		return nil, err
+	}
+
+	var wishlistResponse WishlistResponse
+	err = json.Unmarshal(result, &wishlistResponse)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse wishlist")
+	}
+
+	var items []WishlistItem
+	for _, item := range wishlistResponse.Response.Items {
+		if userInfo.Settings.AllowsGame(access, item.AppID) {
+			items = append(items, item)
+		}
+	}
+
+	return items, nil
+}
+
+func (p *Plugin) runWishlistCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	userIDs, err := p.getGroupUserIDs(args, extra, wishlistMaxUsers)
+	if err != nil {
+		return nil, true, err
+	}
+	if len(userIDs) < 2 {
+		return nil, true, errors.New("at least two connected Steam users are needed to compare wishlists")
+	}
+
+	wishlists := make(map[string][]WishlistItem)
+	for _, userID := range userIDs {
+		items, err := p.getWishlistForUser(userID, accessLibrary)
+		if err != nil {
+			p.API.LogError(errors.Wrapf(err, "unable to get wishlist for %s", userID).Error())
+			continue
+		}
+		wishlists[userID] = items
+	}
+	libraries := p.getOwnedGamesForUsers(userIDs, accessLibrary)
+
+	overlaps := makeWishlistOverlaps(wishlists, libraries)
+	if len(overlaps) > wishlistSuggestions {
+		overlaps = overlaps[:wishlistSuggestions]
+	}
+	if len(overlaps) == 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "No shared wishlist games were found."), false, nil
+	}
+
+	output := fmt.Sprintf("Wishlisted games for %d players:\n\n", len(userIDs))
+	for _, overlap := range overlaps {
+		game := overlap.Game
+		err = game.PopulateStoreData()
+		if err != nil {
+			p.API.LogError(errors.Wrapf(err, "unable to get store data for app %d", game.AppID).Error())
+		}
+		if game.Name == "" {
+			game.Name = game.StoreData.Name
+		}
+
+		output += fmt.Sprintf(" - [%s](%s): %d of you have it wishlisted", game.Name, game.StoreLink(), overlap.Wishlisters)
+		if overlap.Owners > 0 {
+			output += fmt.Sprintf(", %d of you own it", overlap.Owners)
+		}
+		output += fmt.Sprintf(" [%s]", game.StoreData.PriceToString())
+		if genres := game.StoreData.GenresToString(); genres != "" {
+			output += fmt.Sprintf(" [%s]", genres)
+		}
+		output += "\n"
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, output), false, nil
+}
+
+// makeWishlistOverlaps returns the games that are wishlisted by more than one
+// user, or wishlisted by one user and owned by another, sorted by the number
+// of users who want them.
+func makeWishlistOverlaps(wishlists map[string][]WishlistItem, libraries map[string]map[int64]Game) []wishlistOverlap {
+	overlapMap := make(map[int64]*wishlistOverlap)
+	for _, items := range wishlists {
+		for _, item := range items {
+			overlap, ok := overlapMap[item.AppID]
+			if !ok {
+				overlap = &wishlistOverlap{Game: Game{AppID: item.AppID}}
+				overlapMap[item.AppID] = overlap
+			}
+			overlap.Wishlisters++
+		}
+	}
+
+	for _, gameMap := range libraries {
+		for appID, game := range gameMap {
+			overlap, ok := overlapMap[appID]
+			if !ok {
+				continue
+			}
+			overlap.Owners++
+			overlap.Game.Name = game.Name
+		}
+	}
+
+	var overlaps []wishlistOverlap
+	for _, overlap := range overlapMap {
+		if overlap.Wishlisters+overlap.Owners < 2 {
+			continue
+		}
+		overlaps = append(overlaps, *overlap)
+	}
+	sort.Slice(overlaps, func(i, j int) bool {
+		if overlaps[i].Wishlisters != overlaps[j].Wishlisters {
+			return overlaps[i].Wishlisters > overlaps[j].Wishlisters
+		}
+		if overlaps[i].Owners != overlaps[j].Owners {
+			return overlaps[i].Owners > overlaps[j].Owners
+		}
+		return overlaps[i].Game.AppID < overlaps[j].Game.AppID
+	})
+
+	return overlaps
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeWishlistOverlaps(t *testing.T) {
+	wishlists := map[string][]WishlistItem{
+		"alice": {{AppID: 10}, {AppID: 20}, {AppID: 30}},
+		"bob":   {{AppID: 10}, {AppID: 40}},
+		"carol": {{AppID: 10}, {AppID: 20}},
+	}
+	libraries := map[string]map[int64]Game{
+		"bob":   {30: {AppID: 30, Name: "Thirty"}, 50: {AppID: 50, Name: "Fifty"}},
+		"carol": {30: {AppID: 30, Name: "Thirty"}},
+		"dave":  {20: {AppID: 20, Name: "Twenty"}},
+	}
+
+	overlaps := makeWishlistOverlaps(wishlists, libraries)
+	assert.Equal(t, []wishlistOverlap{
+		{Game: Game{AppID: 10}, Wishlisters: 3},
+		{Game: Game{AppID: 20, Name: "Twenty"}, Wishlisters: 2, Owners: 1},
+		{Game: Game{AppID: 30, Name: "Thirty"}, Wishlisters: 1, Owners: 2},
+	}, overlaps)
+
+	t.Run("no overlaps", func(t *testing.T) {
+		overlaps := makeWishlistOverlaps(map[string][]WishlistItem{"alice": {{AppID: 10}}}, nil)
+		assert.Empty(t, overlaps)
+	})
+}
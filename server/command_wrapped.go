@@ -0,0 +1,394 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// WrappedPostedKey is the store prefix recording that a server-wide
+	// wrapped report was posted for a year.
+	WrappedPostedKey = "wrapped_posted_"
+
+	// wrappedTopGames is the number of top games shown in a wrapped report and
+	// used to work out top genres.
+	wrappedTopGames = 5
+)
+
+// wrappedReport is a summary of a year of playtime.
+type wrappedReport struct {
+	Year            int
+	Players         int
+	TotalMinutes    int64
+	DaysPlayed      int
+	GameMinutes     map[int64]int64
+	GameNames       map[int64]string
+	NewGames        int64
+	PlatformMinutes map[string]int64
+	GenreMinutes    map[string]int64
+	LongestStreak   int
+	StreakEnd       string
+}
+
+func newWrappedReport(year int) *wrappedReport {
+	return &wrappedReport{
+		Year:            year,
+		GameMinutes:     make(map[int64]int64),
+		GameNames:       make(map[int64]string),
+		PlatformMinutes: make(map[string]int64),
+		GenreMinutes:    make(map[string]int64),
+	}
+}
+
+func (p *Plugin) runWrappedCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	var server bool
+	if len(args) > 0 && args[0] == "server" {
+		server = true
+		args = args[1:]
+	}
+
+	year := time.Now().UTC().Year()
+	if len(args) > 0 {
+		var err error
+		year, err = strconv.Atoi(args[0])
+		if err != nil {
+			return nil, true, fmt.Errorf("%s is not a valid year", args[0])
+		}
+	}
+
+	var report *wrappedReport
+	var err error
+	if server {
+		report, err = p.getServerWrappedReport(year)
+	} else {
+		report, err = p.getUserWrappedReport(extra.UserId, year, accessSelf)
+		if err == nil {
+			p.populateWrappedGenres(report)
+		}
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	title := fmt.Sprintf("Your %d Steam Wrapped", year)
+	if server {
+		title = fmt.Sprintf("Server %d Steam Wrapped", year)
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, report.String(title)), false, nil
+}
+
+// getUserWrappedReport builds a wrapped report for a single user from their
+// stored playtime snapshots, limited to games their privacy settings allow to
+// be read for the purpose.
+func (p *Plugin) getUserWrappedReport(userID string, year int, access steamDataAccess) (*wrappedReport, error) {
+	keys, err := p.getAllKVKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	return p.getUserWrappedReportFromKeys(userID, year, access, keys)
+}
+
+// getUserWrappedReportFromKeys builds a user's wrapped report, only reading
+// the snapshots that exist among the given store keys.
+func (p *Plugin) getUserWrappedReportFromKeys(userID string, year int, access steamDataAccess, keys []string) (*wrappedReport, error) {
+	userInfo, err := p.getSteamUserForAccess(userID, access)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []*PlaytimeSnapshot
+	for _, key := range wrappedSnapshotKeys(keys, userID, year) {
+		snapshot, err := p.getPlaytimeSnapshotByKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if snapshot != nil {
+			for appID := range snapshot.Games {
+				if !userInfo.Settings.AllowsGame(access, appID) {
+					delete(snapshot.Games, appID)
+				}
+			}
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+
+	return buildWrappedReport(year, snapshots), nil
+}
+
+// wrappedSnapshotKeys returns the keys of a user's snapshots for a year in
+// date order. The last snapshot of the previous year is included as the
+// baseline for the year.
+func wrappedSnapshotKeys(keys []string, userID string, year int) []string {
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
+	end := time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+	prefix := playtimeSnapshotKeyPrefix(userID)
+	var snapshotKeys []string
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		day, ok := parsePlaytimeSnapshotKeyDate(key)
+		if !ok || day.Before(start) || day.After(end) {
+			continue
+		}
+		snapshotKeys = append(snapshotKeys, key)
+	}
+	sort.Strings(snapshotKeys)
+
+	return snapshotKeys
+}
+
+// getServerWrappedReport builds a wrapped report combining every connected
+// user who has not opted out of stats.
+func (p *Plugin) getServerWrappedReport(year int) (*wrappedReport, error) {
+	userIDs, err := p.getSteamUserIDsForAccess(accessStats)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := p.getAllKVKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	report := newWrappedReport(year)
+	for _, userID := range userIDs {
+		userReport, err := p.getUserWrappedReportFromKeys(userID, year, accessStats, keys)
+		if err != nil {
+			p.API.LogError(errors.Wrapf(err, "unable to get wrapped report for %s", userID).Error())
+			continue
+		}
+		report.Merge(userReport)
+	}
+	p.populateWrappedGenres(report)
+
+	return report, nil
+}
+
+// populateWrappedGenres weights the genres of the report's top games by the
+// time spent playing them.
+func (p *Plugin) populateWrappedGenres(report *wrappedReport) {
+	report.GenreMinutes = make(map[string]int64)
+	for _, appID := range report.TopGames(wrappedTopGames) {
+		game := Game{AppID: appID}
+		err := game.PopulateStoreData()
+		if err != nil {
+			p.API.LogError(errors.Wrapf(err, "unable to get store data for app %d", appID).Error())
+			continue
+		}
+
+		for _, genre := range game.StoreData.Genres {
+			report.GenreMinutes[genre.Description] += report.GameMinutes[appID]
+		}
+	}
+}
+
+// buildWrappedReport builds a report from a user's snapshots for a year,
+// sorted by date. Playtime between consecutive snapshots is credited to the
+// day of the later snapshot.
+func buildWrappedReport(year int, snapshots []*PlaytimeSnapshot) *wrappedReport {
+	report := newWrappedReport(year)
+	if len(snapshots) < 2 {
+		return report
+	}
+	report.Players = 1
+
+	var streak int
+	var lastPlayed time.Time
+	for i := 1; i < len(snapshots); i++ {
+		previous, current := snapshots[i-1], snapshots[i]
+
+		var dayMinutes int64
+		for appID, minutes := range playtimeSnapshotDelta(previous, current) {
+			dayMinutes += minutes
+			report.GameMinutes[appID] += minutes
+			report.GameNames[appID] = current.Games[appID].Name
+		}
+		if dayMinutes == 0 {
+			continue
+		}
+		report.TotalMinutes += dayMinutes
+		report.DaysPlayed++
+
+		day, err := time.Parse(playtimeSnapshotDateFormat, current.Date)
+		if err != nil {
+			continue
+		}
+		if !lastPlayed.IsZero() && day.Sub(lastPlayed) == 24*time.Hour {
+			streak++
+		} else {
+			streak = 1
+		}
+		lastPlayed = day
+
+		if streak > report.LongestStreak {
+			report.LongestStreak = streak
+			report.StreakEnd = current.Date
+		}
+	}
+
+	first, last := snapshots[0], snapshots[len(snapshots)-1]
+	for appID, game := range last.Games {
+		firstGame, ok := first.Games[appID]
+		if !ok {
+			report.NewGames++
+		}
+		report.PlatformMinutes["Windows"] += game.WindowsPlaytime - firstGame.WindowsPlaytime
+		report.PlatformMinutes["Mac"] += game.MacPlaytime - firstGame.MacPlaytime
+		report.PlatformMinutes["Linux"] += game.LinuxPlaytime - firstGame.LinuxPlaytime
+	}
+
+	return report
+}
+
+// Merge adds another report's totals to this one. The longest streak is the
+// best individual streak.
+func (r *wrappedReport) Merge(other *wrappedReport) {
+	r.Players += other.Players
+	r.TotalMinutes += other.TotalMinutes
+	r.DaysPlayed += other.DaysPlayed
+	r.NewGames += other.NewGames
+	for appID, minutes := range other.GameMinutes {
+		r.GameMinutes[appID] += minutes
+		r.GameNames[appID] = other.GameNames[appID]
+	}
+	for platform, minutes := range other.PlatformMinutes {
+		r.PlatformMinutes[platform] += minutes
+	}
+	if other.LongestStreak > r.LongestStreak {
+		r.LongestStreak = other.LongestStreak
+		r.StreakEnd = other.StreakEnd
+	}
+}
+
+// TopGames returns the app IDs of the most played games.
+func (r *wrappedReport) TopGames(count int) []int64 {
+	var appIDs []int64
+	for appID := range r.GameMinutes {
+		appIDs = append(appIDs, appID)
+	}
+	sort.Slice(appIDs, func(i, j int) bool {
+		if r.GameMinutes[appIDs[i]] == r.GameMinutes[appIDs[j]] {
+			return appIDs[i] < appIDs[j]
+		}
+		return r.GameMinutes[appIDs[i]] > r.GameMinutes[appIDs[j]]
+	})
+	if len(appIDs) > count {
+		appIDs = appIDs[:count]
+	}
+
+	return appIDs
+}
+
+// String returns the report in markdown.
+func (r *wrappedReport) String(title string) string {
+	output := fmt.Sprintf("#### %s\n\n", title)
+	if r.Players == 0 || r.TotalMinutes == 0 {
+		return output + "No playtime history has been recorded for this year yet.\n"
+	}
+
+	if r.Players > 1 {
+		output += fmt.Sprintf(" - Players: %d\n", r.Players)
+	}
+	output += fmt.Sprintf(" - Hours played: %d\n", r.TotalMinutes/60)
+	if r.Players == 1 {
+		output += fmt.Sprintf(" - Days played: %d\n", r.DaysPlayed)
+	}
+	output += fmt.Sprintf(" - New games acquired: %d\n", r.NewGames)
+	if r.LongestStreak > 0 {
+		output += fmt.Sprintf(" - Longest streak: %d days, ending %s\n", r.LongestStreak, r.StreakEnd)
+	}
+
+	var platforms string
+	for _, platform := range []string{"Windows", "Mac", "Linux"} {
+		if minutes := r.PlatformMinutes[platform]; minutes > 0 {
+			platforms += fmt.Sprintf(" %s %d%%", platform, minutes*100/r.TotalMinutes)
+		}
+	}
+	if platforms != "" {
+		output += fmt.Sprintf(" - Platforms:%s\n", platforms)
+	}
+
+	var genres []string
+	for genre := range r.GenreMinutes {
+		genres = append(genres, genre)
+	}
+	sort.Slice(genres, func(i, j int) bool {
+		if r.GenreMinutes[genres[i]] == r.GenreMinutes[genres[j]] {
+			return genres[i] < genres[j]
+		}
+		return r.GenreMinutes[genres[i]] > r.GenreMinutes[genres[j]]
+	})
+	if len(genres) > 3 {
+		genres = genres[:3]
+	}
+	if len(genres) > 0 {
+		output += fmt.Sprintf(" - Top genres: %s\n", strings.Join(genres, ", "))
+	}
+
+	output += "\nTop games:\n"
+	for i, appID := range r.TopGames(wrappedTopGames) {
+		game := Game{AppID: appID, Name: r.GameNames[appID]}
+		output += fmt.Sprintf("%d. [%s](%s) [%d hours]\n", i+1, game.Name, game.StoreLink(), r.GameMinutes[appID]/60)
+	}
+
+	return output
+}
+
+func wrappedJobInterval(config *configuration) time.Duration {
+	if !config.SteamSummaryEnable {
+		return 0
+	}
+
+	return 24 * time.Hour
+}
+
+// runWrappedJob posts the server-wide wrapped report for the previous year to
+// the summary channel once it's January.
+func (p *Plugin) runWrappedJob() error {
+	now := time.Now().UTC()
+	if now.Month() != time.January {
+		return nil
+	}
+	year := now.Year() - 1
+
+	// The year is claimed before posting so that only one server in a
+	// cluster posts it, and released again if posting fails so that it is
+	// retried on the next run.
+	key := WrappedPostedKey + strconv.Itoa(year)
+	claimed, appErr := p.API.KVCompareAndSet(key, nil, []byte("true"))
+	if appErr != nil {
+		return appErr
+	}
+	if !claimed {
+		return nil
+	}
+
+	err := p.postServerWrappedReport(year)
+	if err != nil {
+		if appErr := p.API.KVDelete(key); appErr != nil {
+			p.API.LogError(errors.Wrap(appErr, "unable to release wrapped report claim in database").Error())
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (p *Plugin) postServerWrappedReport(year int) error {
+	report, err := p.getServerWrappedReport(year)
+	if err != nil {
+		return err
+	}
+
+	return p.PostToChannelByIDAsBot(p.getConfiguration().SteamSummaryChannelID, report.String(fmt.Sprintf("Server %d Steam Wrapped", year)))
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildWrappedReport(t *testing.T) {
+	snapshots := []*PlaytimeSnapshot{
+		{Date: "2025-12-31", Games: map[int64]PlaytimeSnapshotGame{
+			1: {Name: "One", Playtime: 100, WindowsPlaytime: 100},
+		}},
+		{Date: "2026-01-01", Games: map[int64]PlaytimeSnapshotGame{
+			1: {Name: "One", Playtime: 160, WindowsPlaytime: 160},
+		}},
+		{Date: "2026-01-02", Games: map[int64]PlaytimeSnapshotGame{
+			1: {Name: "One", Playtime: 160, WindowsPlaytime: 160},
+			2: {Name: "Two", Playtime: 30, LinuxPlaytime: 30},
+		}},
+		{Date: "2026-01-05", Games: map[int64]PlaytimeSnapshotGame{
+			1: {Name: "One", Playtime: 220, WindowsPlaytime: 220},
+			2: {Name: "Two", Playtime: 30, LinuxPlaytime: 30},
+		}},
+	}
+
+	report := buildWrappedReport(2026, snapshots)
+	assert.Equal(t, 1, report.Players)
+	assert.Equal(t, int64(150), report.TotalMinutes)
+	assert.Equal(t, 3, report.DaysPlayed)
+	assert.Equal(t, map[int64]int64{1: 120, 2: 30}, report.GameMinutes)
+	assert.Equal(t, "Two", report.GameNames[2])
+	assert.Equal(t, int64(1), report.NewGames)
+	assert.Equal(t, int64(120), report.PlatformMinutes["Windows"])
+	assert.Equal(t, int64(30), report.PlatformMinutes["Linux"])
+	assert.Equal(t, 2, report.LongestStreak)
+	assert.Equal(t, "2026-01-02", report.StreakEnd)
+
+	t.Run("single snapshot", func(t *testing.T) {
+		report := buildWrappedReport(2026, snapshots[:1])
+		assert.Equal(t, 0, report.Players)
+		assert.Zero(t, report.TotalMinutes)
+	})
+}
+
+func TestWrappedReportMerge(t *testing.T) {
+	report := newWrappedReport(2026)
+	report.Players = 1
+	report.TotalMinutes = 60
+	report.GameMinutes[1] = 60
+	report.GameNames[1] = "One"
+	report.PlatformMinutes["Windows"] = 60
+	report.LongestStreak = 2
+	report.StreakEnd = "2026-01-02"
+
+	other := newWrappedReport(2026)
+	other.Players = 1
+	other.TotalMinutes = 90
+	other.NewGames = 2
+	other.GameMinutes[1] = 30
+	other.GameMinutes[2] = 60
+	other.GameNames[1] = "One"
+	other.GameNames[2] = "Two"
+	other.PlatformMinutes["Mac"] = 90
+	other.LongestStreak = 5
+	other.StreakEnd = "2026-03-05"
+
+	report.Merge(other)
+	assert.Equal(t, 2, report.Players)
+	assert.Equal(t, int64(150), report.TotalMinutes)
+	assert.Equal(t, int64(2), report.NewGames)
+	assert.Equal(t, map[int64]int64{1: 90, 2: 60}, report.GameMinutes)
+	assert.Equal(t, "Two", report.GameNames[2])
+	assert.Equal(t, map[string]int64{"Windows": 60, "Mac": 90}, report.PlatformMinutes)
+	assert.Equal(t, 5, report.LongestStreak)
+	assert.Equal(t, "2026-03-05", report.StreakEnd)
+}
+
+func TestWrappedReportTopGames(t *testing.T) {
+	report := newWrappedReport(2026)
+	report.GameMinutes[1] = 10
+	report.GameMinutes[2] = 30
+	report.GameMinutes[3] = 30
+	report.GameMinutes[4] = 20
+
+	assert.Equal(t, []int64{2, 3, 4}, report.TopGames(3))
+	assert.Equal(t, []int64{2, 3, 4, 1}, report.TopGames(10))
+	assert.Empty(t, newWrappedReport(2026).TopGames(3))
+}
+
+func TestWrappedSnapshotKeys(t *testing.T) {
+	day := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	keys := []string{
+		playtimeSnapshotKey("user1", day(2026, time.March, 1)),
+		playtimeSnapshotKey("user1", day(2025, time.December, 30)),
+		playtimeSnapshotKey("user1", day(2025, time.December, 31)),
+		playtimeSnapshotKey("user1", day(2027, time.January, 1)),
+		playtimeSnapshotKey("user1", day(2026, time.January, 1)),
+		playtimeSnapshotKey("user2", day(2026, time.January, 1)),
+		playtimeSnapshotKey("user10", day(2026, time.January, 1)),
+		UserInfoCacheKey + "user1",
+	}
+
+	assert.Equal(t, []string{
+		playtimeSnapshotKey("user1", day(2025, time.December, 31)),
+		playtimeSnapshotKey("user1", day(2026, time.January, 1)),
+		playtimeSnapshotKey("user1", day(2026, time.March, 1)),
+	}, wrappedSnapshotKeys(keys, "user1", 2026))
+}
+
+func TestGetUserWrappedReportFromStoredSnapshots(t *testing.T) {
+	userID := model.NewId()
+	year := time.Now().UTC().Year()
+	yearStart := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	store := map[string][]byte{
+		userID + SteamUserKey: makeTestSteamUserBytes(t, userID, defaultUserSettings()),
+	}
+	api := &plugintest.API{}
+	api.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("int64")).Return(nil).Run(func(args mock.Arguments) {
+		key := args.String(0)
+		require.True(t, utf8.RuneCountInString(key) <= model.KEY_VALUE_KEY_MAX_RUNES, "%s is longer than %d runes", key, model.KEY_VALUE_KEY_MAX_RUNES)
+		store[key] = args.Get(1).([]byte)
+	})
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte {
+		return store[key]
+	}, nil)
+	api.On("KVList", 0, kvListPerPage).Return(func(page, perPage int) []string {
+		var keys []string
+		for key := range store {
+			keys = append(keys, key)
+		}
+		return keys
+	}, nil)
+	p := newTestPlugin(api)
+
+	playtimes := []struct {
+		date     time.Time
+		playtime int64
+	}{
+		{yearStart.AddDate(0, 0, -1), 100},
+		{yearStart, 160},
+		{yearStart.AddDate(0, 0, 1), 220},
+	}
+	for _, snapshot := range playtimes {
+		games := map[int64]Game{1: {AppID: 1, Name: "One", Playtime: snapshot.playtime, WindowsPlaytime: snapshot.playtime}}
+		require.NoError(t, p.storePlaytimeSnapshot(userID, snapshot.date, makePlaytimeSnapshot(snapshot.date, games)))
+	}
+
+	report, err := p.getUserWrappedReport(userID, year, accessSelf)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Players)
+	assert.Equal(t, int64(120), report.TotalMinutes)
+	assert.Equal(t, 2, report.DaysPlayed)
+	assert.Equal(t, map[int64]int64{1: 120}, report.GameMinutes)
+	assert.Equal(t, int64(120), report.PlatformMinutes["Windows"])
+}
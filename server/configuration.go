@@ -2,8 +2,9 @@ package main
 
 import (
 	"fmt"
-	"net/url"
 	"reflect"
+	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -22,8 +23,24 @@ import (
 type configuration struct {
 	EncryptionKey         string
 	AllowedEmailDomain    string
+	AllowedTeams          string
+	AllowedRoles          string
+	ExcludedUsers         string
 	SteamSummaryEnable    bool
 	SteamSummaryChannelID string
+	LeaderboardFrequency  string
+	RecentDefaultScope    string
+
+	PresenceAnnouncementsEnable bool
+	PresenceChannelID           string
+
+	AchievementFeedEnable      bool
+	AchievementFeedChannelID   string
+	AchievementRarityThreshold string
+	AchievementDailyCap        string
+
+	PriceAlertsEnable     bool
+	PriceAlertCountryCode string
 }
 
 // Clone shallow copies the configuration. Your implementation may require a deep copy if
@@ -38,9 +55,10 @@ func (c *configuration) IsValid() error {
 		return fmt.Errorf("encryption key cannot be blank")
 	}
 
-	_, err := url.Parse(c.AllowedEmailDomain)
-	if err != nil {
-		return errors.Wrap(err, "invalid AllowedEmailDomain")
+	for _, domain := range c.getAllowedEmailDomains() {
+		if strings.ContainsAny(domain, "@/ ") || !strings.Contains(domain, ".") {
+			return fmt.Errorf("%s is not a valid allowed email domain", domain)
+		}
 	}
 
 	if c.SteamSummaryEnable {
@@ -49,9 +67,143 @@ func (c *configuration) IsValid() error {
 		}
 	}
 
+	if c.PresenceAnnouncementsEnable {
+		if len(c.PresenceChannelID) == 0 {
+			return fmt.Errorf("must specify a presence channel ID when presence announcements are enabled")
+		}
+	}
+
+	if c.AchievementFeedEnable {
+		if len(c.AchievementFeedChannelID) == 0 {
+			return fmt.Errorf("must specify an achievement feed channel ID when the achievement feed is enabled")
+		}
+	}
+
+	if _, err := c.getAchievementRarityThreshold(); err != nil {
+		return err
+	}
+
+	if _, err := c.getAchievementDailyCap(); err != nil {
+		return err
+	}
+
+	if c.PriceAlertCountryCode != "" && len(c.PriceAlertCountryCode) != 2 {
+		return fmt.Errorf("price alert country code must be a two letter country code")
+	}
+
+	switch c.RecentDefaultScope {
+	case "", recentScopeServer, recentScopeTeam, recentScopeChannel:
+	default:
+		return fmt.Errorf("%s is not a valid default recent games scope", c.RecentDefaultScope)
+	}
+
+	switch c.LeaderboardFrequency {
+	case "", leaderboardFrequencyNever, leaderboardFrequencyDaily, leaderboardFrequencyWeekly:
+	default:
+		return fmt.Errorf("%s is not a valid leaderboard frequency", c.LeaderboardFrequency)
+	}
+
 	return nil
 }
 
+// getAchievementRarityThreshold returns the global unlock percentage at or
+// below which achievements are posted to the achievement feed. Defaults to
+// 100, posting every achievement.
+func (c *configuration) getAchievementRarityThreshold() (float64, error) {
+	if c.AchievementRarityThreshold == "" {
+		return 100, nil
+	}
+
+	threshold, err := strconv.ParseFloat(c.AchievementRarityThreshold, 64)
+	if err != nil || threshold < 0 || threshold > 100 {
+		return 0, fmt.Errorf("achievement rarity threshold must be a percentage between 0 and 100")
+	}
+
+	return threshold, nil
+}
+
+// getAchievementDailyCap returns the maximum number of achievements posted to
+// the achievement feed per user per day. Defaults to 5.
+func (c *configuration) getAchievementDailyCap() (int, error) {
+	if c.AchievementDailyCap == "" {
+		return 5, nil
+	}
+
+	dailyCap, err := strconv.Atoi(c.AchievementDailyCap)
+	if err != nil || dailyCap < 1 {
+		return 0, fmt.Errorf("achievement daily cap must be a positive number")
+	}
+
+	return dailyCap, nil
+}
+
+// getPriceAlertCountryCode returns the country code used to check store
+// prices, which determines the currency. Defaults to the US.
+func (c *configuration) getPriceAlertCountryCode() string {
+	if c.PriceAlertCountryCode == "" {
+		return "us"
+	}
+
+	return strings.ToLower(c.PriceAlertCountryCode)
+}
+
+// getRecentDefaultScope returns the scope of /steam recent when none is given.
+// Defaults to the whole server.
+func (c *configuration) getRecentDefaultScope() string {
+	if c.RecentDefaultScope == "" {
+		return recentScopeServer
+	}
+
+	return c.RecentDefaultScope
+}
+
+// splitConfigList splits a comma-separated setting into lowercase values,
+// ignoring blanks.
+func splitConfigList(value string) []string {
+	var values []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item != "" {
+			values = append(values, item)
+		}
+	}
+
+	return values
+}
+
+// getAllowedEmailDomains returns the email domains allowed to use the plugin.
+// Any domain is allowed if none are configured.
+func (c *configuration) getAllowedEmailDomains() []string {
+	var domains []string
+	for _, domain := range splitConfigList(c.AllowedEmailDomain) {
+		domains = append(domains, strings.TrimPrefix(domain, "@"))
+	}
+
+	return domains
+}
+
+// getAllowedTeams returns the names of the teams whose members can use the
+// plugin. Any team is allowed if none are configured.
+func (c *configuration) getAllowedTeams() []string {
+	return splitConfigList(c.AllowedTeams)
+}
+
+// getAllowedRoles returns the roles allowed to use the plugin. Any role is
+// allowed if none are configured.
+func (c *configuration) getAllowedRoles() []string {
+	return splitConfigList(c.AllowedRoles)
+}
+
+// getExcludedUsers returns the usernames that can't use the plugin.
+func (c *configuration) getExcludedUsers() []string {
+	var usernames []string
+	for _, username := range splitConfigList(c.ExcludedUsers) {
+		usernames = append(usernames, strings.TrimPrefix(username, "@"))
+	}
+
+	return usernames
+}
+
 // getConfiguration retrieves the active configuration under lock, making it safe to use
 // concurrently. The active configuration may change underneath the client of this method, but
 // the struct returned by this API call is considered immutable.
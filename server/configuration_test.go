@@ -12,6 +12,7 @@ func TestConfigurationIsValid(t *testing.T) {
 		AllowedEmailDomain:    "mattermost.com",
 		SteamSummaryEnable:    false,
 		SteamSummaryChannelID: "",
+		LeaderboardFrequency:  "never",
 	}
 
 	t.Run("valid", func(t *testing.T) {
@@ -35,4 +36,87 @@ func TestConfigurationIsValid(t *testing.T) {
 			require.NoError(t, config.IsValid())
 		})
 	})
+
+	t.Run("presence announcements", func(t *testing.T) {
+		config := baseConfiguration
+		config.PresenceAnnouncementsEnable = true
+		t.Run("no channel ID", func(t *testing.T) {
+			require.Error(t, config.IsValid())
+		})
+		t.Run("valid", func(t *testing.T) {
+			config.PresenceChannelID = "channel1"
+			require.NoError(t, config.IsValid())
+		})
+	})
+
+	t.Run("achievement feed", func(t *testing.T) {
+		config := baseConfiguration
+		config.AchievementFeedEnable = true
+		t.Run("no channel ID", func(t *testing.T) {
+			require.Error(t, config.IsValid())
+		})
+		t.Run("valid", func(t *testing.T) {
+			config.AchievementFeedChannelID = "channel1"
+			config.AchievementRarityThreshold = "10.5"
+			config.AchievementDailyCap = "3"
+			require.NoError(t, config.IsValid())
+		})
+		t.Run("invalid threshold", func(t *testing.T) {
+			config.AchievementRarityThreshold = "101"
+			require.Error(t, config.IsValid())
+		})
+		t.Run("invalid cap", func(t *testing.T) {
+			config.AchievementRarityThreshold = ""
+			config.AchievementDailyCap = "0"
+			require.Error(t, config.IsValid())
+		})
+	})
+
+	t.Run("price alert country code", func(t *testing.T) {
+		config := baseConfiguration
+		config.PriceAlertCountryCode = "GB"
+		require.NoError(t, config.IsValid())
+		require.Equal(t, "gb", config.getPriceAlertCountryCode())
+
+		config.PriceAlertCountryCode = "GBR"
+		require.Error(t, config.IsValid())
+	})
+
+	t.Run("allowed email domains", func(t *testing.T) {
+		config := baseConfiguration
+		config.AllowedEmailDomain = "mattermost.com, @Example.org,"
+		require.NoError(t, config.IsValid())
+		require.Equal(t, []string{"mattermost.com", "example.org"}, config.getAllowedEmailDomains())
+
+		config.AllowedEmailDomain = "user@mattermost.com"
+		require.Error(t, config.IsValid())
+	})
+
+	t.Run("recent default scope", func(t *testing.T) {
+		config := baseConfiguration
+		require.Equal(t, recentScopeServer, config.getRecentDefaultScope())
+
+		config.RecentDefaultScope = recentScopeChannel
+		require.NoError(t, config.IsValid())
+		require.Equal(t, recentScopeChannel, config.getRecentDefaultScope())
+
+		config.RecentDefaultScope = "galaxy"
+		require.Error(t, config.IsValid())
+	})
+
+	t.Run("leaderboard frequency", func(t *testing.T) {
+		config := baseConfiguration
+		t.Run("blank", func(t *testing.T) {
+			config.LeaderboardFrequency = ""
+			require.NoError(t, config.IsValid())
+		})
+		t.Run("weekly", func(t *testing.T) {
+			config.LeaderboardFrequency = "weekly"
+			require.NoError(t, config.IsValid())
+		})
+		t.Run("invalid", func(t *testing.T) {
+			config.LeaderboardFrequency = "hourly"
+			require.Error(t, config.IsValid())
+		})
+	})
 }
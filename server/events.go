@@ -0,0 +1,427 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// GameEventKey is the store prefix for a game night event.
+	GameEventKey = "game_event_"
+
+	// eventReminderBefore is how long before an event starts that RSVPs are
+	// reminded.
+	eventReminderBefore = 15 * time.Minute
+
+	// eventRetention is how long after starting an event is kept.
+	eventRetention = 24 * time.Hour
+
+	// eventTimeFormat is the format used to display event times.
+	eventTimeFormat = "Mon Jan 2 at 15:04 MST"
+
+	eventRSVPPath = "/api/v1/events/rsvp"
+)
+
+// Event RSVP responses.
+const (
+	RSVPGoing    = "going"
+	RSVPMaybe    = "maybe"
+	RSVPNotGoing = "not_going"
+)
+
+// errNotChannelMember is returned when a user responds to a post in a channel
+// they aren't a member of.
+var errNotChannelMember = errors.New("user is not a member of the channel")
+
+// GameEvent is a scheduled game night in a channel.
+type GameEvent struct {
+	ID        string    `json:"id"`
+	ChannelID string    `json:"channel_id"`
+	PostID    string    `json:"post_id"`
+	CreatorID string    `json:"creator_id"`
+	AppID     int64     `json:"app_id"`
+	GameName  string    `json:"game_name"`
+	StartTime time.Time `json:"start_time"`
+	Timezone  string    `json:"timezone"`
+
+	// RSVPs maps user IDs to their RSVP response.
+	RSVPs map[string]string `json:"rsvps"`
+
+	// OwnsGame records whether users owned the game when they last RSVPed.
+	// Users whose library couldn't be read are left out.
+	OwnsGame map[string]bool `json:"owns_game,omitempty"`
+
+	ReminderSent bool `json:"reminder_sent"`
+}
+
+// Location returns the time zone the event was created in.
+func (e *GameEvent) Location() *time.Location {
+	location, err := time.LoadLocation(e.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+
+	return location
+}
+
+// FormatStartTime returns the event start time in the event's time zone.
+func (e *GameEvent) FormatStartTime() string {
+	return e.StartTime.In(e.Location()).Format(eventTimeFormat)
+}
+
+// UserIDsWithRSVP returns the IDs of users that gave the provided RSVP
+// response, sorted for stable output.
+func (e *GameEvent) UserIDsWithRSVP(response string) []string {
+	var userIDs []string
+	for userID, rsvp := range e.RSVPs {
+		if rsvp == response {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	sort.Strings(userIDs)
+
+	return userIDs
+}
+
+func gameEventKey(eventID string) string {
+	return GameEventKey + eventID
+}
+
+func (p *Plugin) getGameEvent(eventID string) (*GameEvent, []byte, error) {
+	eventBytes, appErr := p.API.KVGet(gameEventKey(eventID))
+	if appErr != nil {
+		return nil, nil, errors.Wrap(appErr, "unable to get event")
+	}
+	if eventBytes == nil {
+		return nil, nil, errors.New("event not found")
+	}
+
+	var event GameEvent
+	err := json.Unmarshal(eventBytes, &event)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to parse event")
+	}
+
+	return &event, eventBytes, nil
+}
+
+// storeGameEvent stores an event until a day after it starts. If oldBytes is
+// provided, the event is only stored if it hasn't changed since it was read.
+func (p *Plugin) storeGameEvent(event *GameEvent, oldBytes []byte) (bool, error) {
+	eventBytes, err := json.Marshal(event)
+	if err != nil {
+		return false, errors.Wrap(err, "unable to marshal event")
+	}
+
+	// Updates keep the expiry set when the event was created.
+	if oldBytes != nil {
+		stored, appErr := p.API.KVCompareAndSet(gameEventKey(event.ID), oldBytes, eventBytes)
+		if appErr != nil {
+			return false, errors.Wrap(appErr, "unable to store event in database")
+		}
+		return stored, nil
+	}
+
+	expiry := int64(time.Until(event.StartTime.Add(eventRetention)).Seconds())
+	if expiry < 1 {
+		expiry = 1
+	}
+	appErr := p.API.KVSetWithExpiry(gameEventKey(event.ID), eventBytes, expiry)
+	if appErr != nil {
+		return false, errors.Wrap(appErr, "unable to store event in database")
+	}
+
+	return true, nil
+}
+
+// getGameEvents returns all stored events, soonest first.
+func (p *Plugin) getGameEvents() ([]*GameEvent, error) {
+	keys, err := p.getAllKVKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*GameEvent
+	for _, key := range keys {
+		if !strings.HasPrefix(key, GameEventKey) {
+			continue
+		}
+
+		event, _, err := p.getGameEvent(strings.TrimPrefix(key, GameEventKey))
+		if err != nil {
+			p.API.LogError(errors.Wrapf(err, "unable to get event %s", key).Error())
+			continue
+		}
+		events = append(events, event)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].StartTime.Before(events[j].StartTime)
+	})
+
+	return events, nil
+}
+
+// setGameEventRSVP records a user's RSVP, retrying if the event is updated
+// concurrently.
+func (p *Plugin) setGameEventRSVP(eventID, userID, response string) (*GameEvent, error) {
+	// Only the responding user's library is read, so that the event card can
+	// be updated without reading the library of everyone attending.
+	var owns, ownsKnown bool
+	for i := 0; i < StoreSteamRetries; i++ {
+		event, eventBytes, err := p.getGameEvent(eventID)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			if _, appErr := p.API.GetChannelMember(event.ChannelID, userID); appErr != nil {
+				return nil, errNotChannelMember
+			}
+			if response != RSVPNotGoing {
+				owns, ownsKnown = p.getGameOwnership(userID, event.AppID)
+			}
+		}
+
+		event.SetRSVP(userID, response, owns, ownsKnown)
+
+		stored, err := p.storeGameEvent(event, eventBytes)
+		if err != nil {
+			return nil, err
+		}
+		if stored {
+			return event, nil
+		}
+	}
+
+	return nil, errors.New("unable to store RSVP after several attempts")
+}
+
+// SetRSVP records a user's RSVP response and whether they own the game.
+func (e *GameEvent) SetRSVP(userID, response string, owns, ownsKnown bool) {
+	if e.RSVPs == nil {
+		e.RSVPs = make(map[string]string)
+	}
+	e.RSVPs[userID] = response
+
+	if !ownsKnown {
+		delete(e.OwnsGame, userID)
+		return
+	}
+	if e.OwnsGame == nil {
+		e.OwnsGame = make(map[string]bool)
+	}
+	e.OwnsGame[userID] = owns
+}
+
+// getGameOwnership returns whether the user owns a game. False is returned
+// for ok if their library can't be read.
+func (p *Plugin) getGameOwnership(userID string, appID int64) (owns bool, ok bool) {
+	gameMap, err := p.getOwnedGamesForUser(userID, accessLibrary)
+	if err == errSteamDataPrivate {
+		return false, false
+	}
+	if err != nil {
+		p.API.LogError(errors.Wrapf(err, "unable to get owned games for %s", userID).Error())
+		return false, false
+	}
+
+	_, owns = gameMap[appID]
+	return owns, true
+}
+
+// makeGameEventPost returns the interactive card for an event.
+func (p *Plugin) makeGameEventPost(event *GameEvent) *model.Post {
+	game := Game{AppID: event.AppID, Name: event.GameName}
+
+	text := fmt.Sprintf("**%s**\n", event.FormatStartTime())
+	text += p.formatGameEventRSVPs(event)
+
+	actionURL := p.getPluginURL(eventRSVPPath)
+	makeAction := func(name, response string) *model.PostAction {
+		return &model.PostAction{
+			Name: name,
+			Integration: &model.PostActionIntegration{
+				URL: actionURL,
+				Context: map[string]interface{}{
+					"event_id": event.ID,
+					"response": response,
+				},
+			},
+		}
+	}
+
+	post := &model.Post{
+		Id:        event.PostID,
+		UserId:    p.BotUserID,
+		ChannelId: event.ChannelID,
+	}
+	model.ParseSlackAttachment(post, []*model.SlackAttachment{{
+		Title:     fmt.Sprintf("Game night: %s", game.Name),
+		TitleLink: game.StoreLink(),
+		ThumbURL:  gameCapsuleImgURL(fmt.Sprintf("%d", game.AppID)),
+		Text:      text,
+		Actions: []*model.PostAction{
+			makeAction("Going", RSVPGoing),
+			makeAction("Maybe", RSVPMaybe),
+			makeAction("Can't go", RSVPNotGoing),
+		},
+	}})
+
+	return post
+}
+
+// formatGameEventRSVPs lists RSVPs along with whether those attending owned
+// the game when they RSVPed.
+func (p *Plugin) formatGameEventRSVPs(event *GameEvent) string {
+	formatAttending := func(userIDs []string) string {
+		var names []string
+		for _, userID := range userIDs {
+			name := p.getUserMention(userID)
+			if owns, ok := event.OwnsGame[userID]; !ok {
+				name += " (library unavailable)"
+			} else if !owns {
+				name += " (doesn't own the game)"
+			}
+			names = append(names, name)
+		}
+		if len(names) == 0 {
+			return "-"
+		}
+		return strings.Join(names, ", ")
+	}
+
+	var notGoing []string
+	for _, userID := range event.UserIDsWithRSVP(RSVPNotGoing) {
+		notGoing = append(notGoing, p.getUserMention(userID))
+	}
+	if len(notGoing) == 0 {
+		notGoing = []string{"-"}
+	}
+
+	output := fmt.Sprintf("Going: %s\n", formatAttending(event.UserIDsWithRSVP(RSVPGoing)))
+	output += fmt.Sprintf("Maybe: %s\n", formatAttending(event.UserIDsWithRSVP(RSVPMaybe)))
+	output += fmt.Sprintf("Can't go: %s", strings.Join(notGoing, ", "))
+
+	return output
+}
+
+// getUserMention returns an @mention for a user.
+func (p *Plugin) getUserMention(userID string) string {
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		p.API.LogError(errors.Wrapf(appErr, "unable to get user %s", userID).Error())
+		return "unknown user"
+	}
+
+	return "@" + user.Username
+}
+
+// getUserLocation returns the user's preferred time zone, or UTC if it isn't
+// set.
+func (p *Plugin) getUserLocation(userID string) *time.Location {
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		return time.UTC
+	}
+
+	location, err := time.LoadLocation(model.GetPreferredTimezone(user.Timezone))
+	if err != nil {
+		return time.UTC
+	}
+
+	return location
+}
+
+// parseEventTime parses an event start time relative to now. Supported
+// formats are a duration such as "2h30m", a time such as "20:00" for its next
+// occurrence, and a date and time such as "2019-10-31 20:00".
+func parseEventTime(value string, now time.Time, location *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(value), "in "))
+	now = now.In(location)
+
+	if duration, err := time.ParseDuration(value); err == nil {
+		if duration <= 0 {
+			return time.Time{}, errors.New("event must start in the future")
+		}
+		return now.Add(duration), nil
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02 15:04", value, location); err == nil {
+		if !t.After(now) {
+			return time.Time{}, errors.New("event must start in the future")
+		}
+		return t, nil
+	}
+
+	if t, err := time.ParseInLocation("15:04", value, location); err == nil {
+		start := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, location)
+		if !start.After(now) {
+			start = start.AddDate(0, 0, 1)
+		}
+		return start, nil
+	}
+
+	return time.Time{}, fmt.Errorf("%s is not a valid time, use a duration like 2h, a time like 20:00 or a date and time like 2019-10-31 20:00", value)
+}
+
+func eventsJobInterval(config *configuration) time.Duration {
+	return jobCheckInterval
+}
+
+// runEventsJob reminds RSVPs of events that are about to start.
+func (p *Plugin) runEventsJob() error {
+	events, err := p.getGameEvents()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, event := range events {
+		if event.ReminderSent || event.StartTime.Sub(now) > eventReminderBefore || now.After(event.StartTime) {
+			continue
+		}
+
+		err = p.sendGameEventReminders(event.ID)
+		if err != nil {
+			p.API.LogError(errors.Wrapf(err, "unable to send reminders for event %s", event.ID).Error())
+		}
+	}
+
+	return nil
+}
+
+func (p *Plugin) sendGameEventReminders(eventID string) error {
+	event, eventBytes, err := p.getGameEvent(eventID)
+	if err != nil {
+		return err
+	}
+
+	// The event is marked first so that reminders aren't sent twice if the
+	// event is updated concurrently.
+	event.ReminderSent = true
+	stored, err := p.storeGameEvent(event, eventBytes)
+	if err != nil || !stored {
+		return err
+	}
+
+	game := Game{AppID: event.AppID, Name: event.GameName}
+	message := fmt.Sprintf("Reminder: game night for [%s](%s) starts %s.", game.Name, game.StoreLink(), event.FormatStartTime())
+	if event.PostID != "" {
+		message += fmt.Sprintf(" [Event](%s)", p.getPermalink(event.PostID))
+	}
+
+	attending := append(event.UserIDsWithRSVP(RSVPGoing), event.UserIDsWithRSVP(RSVPMaybe)...)
+	for _, userID := range attending {
+		err = p.PostBotDM(userID, message)
+		if err != nil {
+			p.API.LogError(errors.Wrapf(err, "unable to send event reminder to %s", userID).Error())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEventTime(t *testing.T) {
+	location, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	now := time.Date(2019, 10, 1, 18, 0, 0, 0, location)
+
+	t.Run("duration", func(t *testing.T) {
+		start, err := parseEventTime("2h30m", now, location)
+		require.NoError(t, err)
+		assert.Equal(t, now.Add(150*time.Minute), start)
+
+		start, err = parseEventTime("in 1h", now, location)
+		require.NoError(t, err)
+		assert.Equal(t, now.Add(time.Hour), start)
+	})
+
+	t.Run("time later today", func(t *testing.T) {
+		start, err := parseEventTime("20:00", now, location)
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2019, 10, 1, 20, 0, 0, 0, location), start)
+	})
+
+	t.Run("time tomorrow", func(t *testing.T) {
+		start, err := parseEventTime("09:30", now, location)
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2019, 10, 2, 9, 30, 0, 0, location), start)
+	})
+
+	t.Run("date and time", func(t *testing.T) {
+		start, err := parseEventTime("2019-10-31 20:00", now, location)
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2019, 10, 31, 20, 0, 0, 0, location), start)
+	})
+
+	t.Run("past and invalid times", func(t *testing.T) {
+		_, err := parseEventTime("2019-09-30 20:00", now, location)
+		assert.Error(t, err)
+		_, err = parseEventTime("-1h", now, location)
+		assert.Error(t, err)
+		_, err = parseEventTime("tonight", now, location)
+		assert.Error(t, err)
+	})
+}
+
+func TestGameEventSetRSVP(t *testing.T) {
+	event := &GameEvent{}
+
+	event.SetRSVP("user1", RSVPGoing, true, true)
+	event.SetRSVP("user2", RSVPMaybe, false, true)
+	event.SetRSVP("user3", RSVPGoing, false, false)
+	assert.Equal(t, map[string]string{"user1": RSVPGoing, "user2": RSVPMaybe, "user3": RSVPGoing}, event.RSVPs)
+	assert.Equal(t, map[string]bool{"user1": true, "user2": false}, event.OwnsGame)
+	assert.Equal(t, []string{"user1", "user3"}, event.UserIDsWithRSVP(RSVPGoing))
+
+	event.SetRSVP("user1", RSVPNotGoing, false, false)
+	assert.Equal(t, RSVPNotGoing, event.RSVPs["user1"])
+	assert.NotContains(t, event.OwnsGame, "user1")
+}
+
+func TestStoreGameEvent(t *testing.T) {
+	event := &GameEvent{ID: "event1", StartTime: time.Now().Add(time.Hour)}
+
+	t.Run("create sets an expiry", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("KVSetWithExpiry", gameEventKey("event1"), mock.Anything, mock.AnythingOfType("int64")).Return(nil)
+		defer api.AssertExpectations(t)
+
+		stored, err := newTestPlugin(api).storeGameEvent(event, nil)
+		require.NoError(t, err)
+		assert.True(t, stored)
+	})
+
+	t.Run("update only compares and sets", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("KVCompareAndSet", gameEventKey("event1"), []byte("old"), mock.Anything).Return(false, nil)
+		defer api.AssertExpectations(t)
+
+		stored, err := newTestPlugin(api).storeGameEvent(event, []byte("old"))
+		require.NoError(t, err)
+		assert.False(t, stored)
+	})
+}
+
+func TestSetGameEventRSVP(t *testing.T) {
+	eventBytes, err := json.Marshal(&GameEvent{ID: "event1", ChannelID: "channel1", AppID: 10, StartTime: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+
+	t.Run("non-members can't RSVP", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("KVGet", gameEventKey("event1")).Return(eventBytes, nil)
+		api.On("GetChannelMember", "channel1", "user1").Return(nil, &model.AppError{Message: "not found"})
+		defer api.AssertExpectations(t)
+
+		_, err := newTestPlugin(api).setGameEventRSVP("event1", "user1", RSVPGoing)
+		assert.Equal(t, errNotChannelMember, err)
+		api.AssertNotCalled(t, "KVCompareAndSet", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("members can RSVP", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("KVGet", gameEventKey("event1")).Return(eventBytes, nil)
+		api.On("GetChannelMember", "channel1", "user1").Return(&model.ChannelMember{ChannelId: "channel1", UserId: "user1"}, nil)
+		api.On("KVCompareAndSet", gameEventKey("event1"), eventBytes, mock.Anything).Return(true, nil)
+		defer api.AssertExpectations(t)
+
+		event, err := newTestPlugin(api).setGameEventRSVP("event1", "user1", RSVPNotGoing)
+		require.NoError(t, err)
+		assert.Equal(t, RSVPNotGoing, event.RSVPs["user1"])
+	})
+}
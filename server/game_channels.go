@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// GameChannelKey is the store prefix for the channel linked to a game in
+	// a team.
+	GameChannelKey = "game_channel_"
+
+	// gameChannelsInterval is how often libraries are checked for new owners
+	// of games with auto-invite channels.
+	gameChannelsInterval = 6 * time.Hour
+)
+
+var channelNameInvalidCharacters = regexp.MustCompile(`[^a-z0-9]+`)
+
+// GameChannel is a channel dedicated to a game in a team.
+type GameChannel struct {
+	AppID      int64  `json:"app_id"`
+	GameName   string `json:"game_name"`
+	TeamID     string `json:"team_id"`
+	ChannelID  string `json:"channel_id"`
+	CreatorID  string `json:"creator_id"`
+	AutoInvite bool   `json:"auto_invite"`
+
+	// InvitedUserIDs are the users already invited, so that users who leave
+	// the channel aren't invited again.
+	InvitedUserIDs []string `json:"invited_user_ids"`
+}
+
+// WasInvited returns true if the user was already invited to the channel.
+func (c *GameChannel) WasInvited(userID string) bool {
+	for _, invitedUserID := range c.InvitedUserIDs {
+		if invitedUserID == userID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// gameChannelName returns a channel name for a game.
+func gameChannelName(game *Game) string {
+	name := channelNameInvalidCharacters.ReplaceAllString(strings.ToLower(game.Name), "-")
+	name = strings.Trim(name, "-")
+	if len(name) > model.CHANNEL_NAME_MAX_LENGTH {
+		name = strings.Trim(name[:model.CHANNEL_NAME_MAX_LENGTH], "-")
+	}
+	if len(name) < 2 {
+		name = fmt.Sprintf("game-%d", game.AppID)
+	}
+
+	return name
+}
+
+func gameChannelKey(teamID string, appID int64) string {
+	return fmt.Sprintf("%s%s_%d", GameChannelKey, teamID, appID)
+}
+
+func (p *Plugin) getGameChannel(teamID string, appID int64) (*GameChannel, error) {
+	return p.getGameChannelByKey(gameChannelKey(teamID, appID))
+}
+
+func (p *Plugin) getGameChannelByKey(key string) (*GameChannel, error) {
+	channelBytes, appErr := p.API.KVGet(key)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "unable to get game channel")
+	}
+	if channelBytes == nil {
+		return nil, nil
+	}
+
+	var gameChannel GameChannel
+	err := json.Unmarshal(channelBytes, &gameChannel)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse game channel")
+	}
+
+	return &gameChannel, nil
+}
+
+func (p *Plugin) storeGameChannel(gameChannel *GameChannel) error {
+	channelBytes, err := json.Marshal(gameChannel)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal game channel")
+	}
+
+	appErr := p.API.KVSet(gameChannelKey(gameChannel.TeamID, gameChannel.AppID), channelBytes)
+	if appErr != nil {
+		return errors.Wrap(appErr, "unable to store game channel in database")
+	}
+
+	return nil
+}
+
+func (p *Plugin) deleteGameChannel(gameChannel *GameChannel) error {
+	appErr := p.API.KVDelete(gameChannelKey(gameChannel.TeamID, gameChannel.AppID))
+	if appErr != nil {
+		return errors.Wrap(appErr, "unable to delete game channel in database")
+	}
+
+	return nil
+}
+
+// getGameChannels returns the game channels in a team, or in every team if
+// teamID is empty, sorted by game name.
+func (p *Plugin) getGameChannels(teamID string) ([]*GameChannel, error) {
+	keys, err := p.getAllKVKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := GameChannelKey + teamID
+	var gameChannels []*GameChannel
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		gameChannel, err := p.getGameChannelByKey(key)
+		if err != nil {
+			p.API.LogError(errors.Wrapf(err, "unable to get game channel %s", key).Error())
+			continue
+		}
+		if gameChannel != nil {
+			gameChannels = append(gameChannels, gameChannel)
+		}
+	}
+	sort.Slice(gameChannels, func(i, j int) bool {
+		return gameChannels[i].GameName < gameChannels[j].GameName
+	})
+
+	return gameChannels, nil
+}
+
+// getGameOwnerLibraries returns the libraries of connected users who share
+// them, limited to members of the team unless teamID is empty.
+func (p *Plugin) getGameOwnerLibraries(teamID string) (map[string]map[int64]Game, error) {
+	userIDs, err := p.getSteamUserIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	if teamID != "" {
+		var teamUserIDs []string
+		for _, userID := range userIDs {
+			if _, appErr := p.API.GetTeamMember(teamID, userID); appErr == nil {
+				teamUserIDs = append(teamUserIDs, userID)
+			}
+		}
+		userIDs = teamUserIDs
+	}
+
+	return p.getOwnedGamesForUsers(userIDs, accessLibrary), nil
+}
+
+// inviteGameOwners adds team members in libraries who own the game to the
+// channel. The IDs of invited users are returned.
+func (p *Plugin) inviteGameOwners(gameChannel *GameChannel, libraries map[string]map[int64]Game) []string {
+	var invited []string
+	for userID, gameMap := range libraries {
+		if gameChannel.WasInvited(userID) {
+			continue
+		}
+		if _, ok := gameMap[gameChannel.AppID]; !ok {
+			continue
+		}
+		if _, appErr := p.API.GetTeamMember(gameChannel.TeamID, userID); appErr != nil {
+			continue
+		}
+
+		gameChannel.InvitedUserIDs = append(gameChannel.InvitedUserIDs, userID)
+		if _, appErr := p.API.GetChannelMember(gameChannel.ChannelID, userID); appErr == nil {
+			continue
+		}
+
+		_, appErr := p.API.AddChannelMember(gameChannel.ChannelID, userID)
+		if appErr != nil {
+			p.API.LogError(errors.Wrapf(appErr, "unable to add %s to channel %s", userID, gameChannel.ChannelID).Error())
+			continue
+		}
+		invited = append(invited, userID)
+	}
+
+	return invited
+}
+
+func gameChannelsJobInterval(config *configuration) time.Duration {
+	return gameChannelsInterval
+}
+
+// runGameChannelsJob invites new owners of games to auto-invite channels.
+func (p *Plugin) runGameChannelsJob() error {
+	gameChannels, err := p.getGameChannels("")
+	if err != nil {
+		return err
+	}
+
+	var libraries map[string]map[int64]Game
+	for _, gameChannel := range gameChannels {
+		if !gameChannel.AutoInvite {
+			continue
+		}
+
+		// Libraries are only read once per run and only if there is an
+		// auto-invite channel.
+		if libraries == nil {
+			libraries, err = p.getGameOwnerLibraries("")
+			if err != nil {
+				return err
+			}
+		}
+
+		previouslyInvited := len(gameChannel.InvitedUserIDs)
+		p.inviteGameOwners(gameChannel, libraries)
+		if len(gameChannel.InvitedUserIDs) == previouslyInvited {
+			continue
+		}
+
+		err = p.addGameChannelInvites(gameChannel.TeamID, gameChannel.AppID, gameChannel.InvitedUserIDs[previouslyInvited:])
+		if err != nil {
+			p.API.LogError(errors.Wrapf(err, "unable to store game channel for app %d", gameChannel.AppID).Error())
+		}
+	}
+
+	return nil
+}
+
+// addGameChannelInvites records newly invited users on a game channel. The
+// channel is read again and only updated if it hasn't changed since, so that
+// a channel unlinked in the meantime stays unlinked.
+func (p *Plugin) addGameChannelInvites(teamID string, appID int64, userIDs []string) error {
+	key := gameChannelKey(teamID, appID)
+	channelBytes, appErr := p.API.KVGet(key)
+	if appErr != nil {
+		return errors.Wrap(appErr, "unable to get game channel")
+	}
+	if channelBytes == nil {
+		return nil
+	}
+
+	var gameChannel GameChannel
+	err := json.Unmarshal(channelBytes, &gameChannel)
+	if err != nil {
+		return errors.Wrap(err, "unable to parse game channel")
+	}
+	for _, userID := range userIDs {
+		if !gameChannel.WasInvited(userID) {
+			gameChannel.InvitedUserIDs = append(gameChannel.InvitedUserIDs, userID)
+		}
+	}
+
+	newBytes, err := json.Marshal(&gameChannel)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal game channel")
+	}
+
+	// A channel changed in the meantime is left for the next run, which
+	// won't invite users again since they are now channel members.
+	_, appErr = p.API.KVCompareAndSet(key, channelBytes, newBytes)
+	if appErr != nil {
+		return errors.Wrap(appErr, "unable to store game channel in database")
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGameChannelName(t *testing.T) {
+	for _, tc := range []struct {
+		game     Game
+		expected string
+	}{
+		{Game{AppID: 10, Name: "Counter-Strike"}, "counter-strike"},
+		{Game{AppID: 620, Name: "Portal 2"}, "portal-2"},
+		{Game{AppID: 1, Name: "  Tom Clancy's Rainbow Six® Siege!  "}, "tom-clancy-s-rainbow-six-siege"},
+		{Game{AppID: 2, Name: "X"}, "game-2"},
+		{Game{AppID: 3, Name: "東方"}, "game-3"},
+	} {
+		assert.Equal(t, tc.expected, gameChannelName(&tc.game), tc.game.Name)
+	}
+
+	long := gameChannelName(&Game{AppID: 4, Name: strings.Repeat("ab ", model.CHANNEL_NAME_MAX_LENGTH)})
+	assert.True(t, len(long) <= model.CHANNEL_NAME_MAX_LENGTH)
+	assert.False(t, strings.HasSuffix(long, "-"))
+}
+
+func TestAddGameChannelInvites(t *testing.T) {
+	key := gameChannelKey("team1", 10)
+
+	t.Run("unlinked channel is not recreated", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("KVGet", key).Return(nil, nil)
+		defer api.AssertExpectations(t)
+
+		err := newTestPlugin(api).addGameChannelInvites("team1", 10, []string{"user2"})
+		require.NoError(t, err)
+	})
+
+	t.Run("invites are added to the current channel", func(t *testing.T) {
+		channelBytes, err := json.Marshal(&GameChannel{AppID: 10, TeamID: "team1", ChannelID: "channel1", InvitedUserIDs: []string{"user1"}})
+		require.NoError(t, err)
+		expectedBytes, err := json.Marshal(&GameChannel{AppID: 10, TeamID: "team1", ChannelID: "channel1", InvitedUserIDs: []string{"user1", "user2"}})
+		require.NoError(t, err)
+
+		api := &plugintest.API{}
+		api.On("KVGet", key).Return(channelBytes, nil)
+		api.On("KVCompareAndSet", key, channelBytes, expectedBytes).Return(true, nil)
+		defer api.AssertExpectations(t)
+
+		err = newTestPlugin(api).addGameChannelInvites("team1", 10, []string{"user1", "user2"})
+		require.NoError(t, err)
+	})
+}
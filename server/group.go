@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/pkg/errors"
+)
+
+// getGroupUserIDs returns the connected users, including the requesting user,
+// that a group command applies to. When no usernames are provided, the
+// connected members of the current channel who share their library are used,
+// up to maxUsers.
+func (p *Plugin) getGroupUserIDs(args []string, extra *model.CommandArgs, maxUsers int) ([]string, error) {
+	connected, err := p.getSteamUserIDs()
+	if err != nil {
+		return nil, err
+	}
+	connectedSet := make(map[string]bool)
+	for _, userID := range connected {
+		connectedSet[userID] = true
+	}
+	shared, err := p.getSteamUserIDsForAccess(accessLibrary)
+	if err != nil {
+		return nil, err
+	}
+	sharedSet := make(map[string]bool)
+	for _, userID := range shared {
+		sharedSet[userID] = true
+	}
+	if !connectedSet[extra.UserId] {
+		return nil, errors.New("you must connect your Steam account first")
+	}
+
+	userIDs := []string{extra.UserId}
+	seen := map[string]bool{extra.UserId: true}
+
+	if len(args) > 0 {
+		if len(args) >= maxUsers {
+			return nil, fmt.Errorf("this command is currently limited to %d users", maxUsers)
+		}
+
+		for _, arg := range args {
+			username := strings.TrimPrefix(arg, "@")
+			user, appErr := p.API.GetUserByUsername(username)
+			if appErr != nil {
+				return nil, errors.Wrapf(appErr, "unable to get user %s", username)
+			}
+			if !connectedSet[user.Id] {
+				return nil, fmt.Errorf("%s has not connected a Steam account", username)
+			}
+			if !sharedSet[user.Id] && user.Id != extra.UserId {
+				return nil, fmt.Errorf("%s's Steam library is private", username)
+			}
+			if seen[user.Id] {
+				continue
+			}
+			seen[user.Id] = true
+			userIDs = append(userIDs, user.Id)
+		}
+
+		return userIDs, nil
+	}
+
+	for page := 0; ; page++ {
+		users, appErr := p.API.GetUsersInChannel(extra.ChannelId, "username", page, 200)
+		if appErr != nil {
+			return nil, errors.Wrap(appErr, "unable to get channel members")
+		}
+
+		for _, user := range users {
+			if !sharedSet[user.Id] || seen[user.Id] {
+				continue
+			}
+			seen[user.Id] = true
+			userIDs = append(userIDs, user.Id)
+			if len(userIDs) == maxUsers {
+				return userIDs, nil
+			}
+		}
+
+		if len(users) < 200 {
+			break
+		}
+	}
+
+	return userIDs, nil
+}
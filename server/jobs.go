@@ -0,0 +1,171 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// jobKeyPrefix is the store prefix for the last run time of a background
+	// job.
+	jobKeyPrefix = "job_last_run_"
+
+	// jobCheckInterval is how often background jobs are checked to see if they
+	// are due to run.
+	jobCheckInterval = time.Minute
+)
+
+// backgroundJob is a task that the plugin runs periodically.
+type backgroundJob struct {
+	Name string
+
+	// Interval returns how often the job should run for a given
+	// configuration. A zero interval disables the job.
+	Interval func(config *configuration) time.Duration
+
+	Run func() error
+}
+
+func (p *Plugin) getBackgroundJobs() []backgroundJob {
+	return []backgroundJob{
+		{
+			Name:     "leaderboard",
+			Interval: leaderboardJobInterval,
+			Run:      p.runLeaderboardJob,
+		},
+		{
+			Name:     "playtime_snapshot",
+			Interval: playtimeSnapshotJobInterval,
+			Run:      p.runPlaytimeSnapshotJob,
+		},
+		{
+			Name:     "wrapped",
+			Interval: wrappedJobInterval,
+			Run:      p.runWrappedJob,
+		},
+		{
+			Name:     "presence",
+			Interval: presenceJobInterval,
+			Run:      p.runPresenceJob,
+		},
+		{
+			Name:     "achievement_feed",
+			Interval: achievementFeedJobInterval,
+			Run:      p.runAchievementFeedJob,
+		},
+		{
+			Name:     "price_alerts",
+			Interval: priceAlertsJobInterval,
+			Run:      p.runPriceAlertsJob,
+		},
+		{
+			Name:     "news",
+			Interval: newsJobInterval,
+			Run:      p.runNewsJob,
+		},
+		{
+			Name:     "events",
+			Interval: eventsJobInterval,
+			Run:      p.runEventsJob,
+		},
+		{
+			Name:     "game_channels",
+			Interval: gameChannelsJobInterval,
+			Run:      p.runGameChannelsJob,
+		},
+	}
+}
+
+// startBackgroundJobs starts checking for due background jobs until
+// stopBackgroundJobs is called.
+func (p *Plugin) startBackgroundJobs() {
+	p.stopJobs = make(chan struct{})
+	p.jobsDone = make(chan struct{})
+
+	go func() {
+		defer close(p.jobsDone)
+
+		ticker := time.NewTicker(jobCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			p.runDueBackgroundJobs()
+
+			select {
+			case <-ticker.C:
+			case <-p.stopJobs:
+				return
+			}
+		}
+	}()
+}
+
+// stopBackgroundJobs stops background job checks and waits for any running
+// job to finish.
+func (p *Plugin) stopBackgroundJobs() {
+	if p.stopJobs == nil {
+		return
+	}
+
+	close(p.stopJobs)
+	<-p.jobsDone
+	p.stopJobs = nil
+}
+
+func (p *Plugin) runDueBackgroundJobs() {
+	config := p.getConfiguration()
+	if config.IsValid() != nil {
+		return
+	}
+
+	for _, job := range p.getBackgroundJobs() {
+		interval := job.Interval(config)
+		if interval == 0 {
+			continue
+		}
+
+		claimed, err := p.claimBackgroundJob(job.Name, interval, time.Now())
+		if err != nil {
+			p.API.LogError(errors.Wrapf(err, "unable to claim background job %s", job.Name).Error())
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		err = job.Run()
+		if err != nil {
+			p.API.LogError(errors.Wrapf(err, "background job %s failed", job.Name).Error())
+		}
+	}
+}
+
+// claimBackgroundJob returns true if the job is due and was atomically marked
+// as run, ensuring only one plugin instance in a cluster runs it.
+func (p *Plugin) claimBackgroundJob(name string, interval time.Duration, now time.Time) (bool, error) {
+	key := jobKeyPrefix + name
+
+	lastRunBytes, appErr := p.API.KVGet(key)
+	if appErr != nil {
+		return false, appErr
+	}
+
+	if lastRunBytes != nil {
+		lastRun, err := strconv.ParseInt(string(lastRunBytes), 10, 64)
+		if err != nil {
+			return false, errors.Wrap(err, "unable to parse last run time")
+		}
+		if now.Sub(time.Unix(lastRun, 0)) < interval {
+			return false, nil
+		}
+	}
+
+	claimed, appErr := p.API.KVCompareAndSet(key, lastRunBytes, []byte(strconv.FormatInt(now.Unix(), 10)))
+	if appErr != nil {
+		return false, appErr
+	}
+
+	return claimed, nil
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// LFGKey is the store prefix for a looking-for-group post.
+	LFGKey = "lfg_"
+
+	// lfgRetention is how long a looking-for-group post can be joined.
+	lfgRetention = 12 * time.Hour
+
+	// lfgDefaultSlots is the number of players looked for when not provided.
+	lfgDefaultSlots = 3
+
+	// lfgMaxSlots is the maximum number of players that can be looked for.
+	lfgMaxSlots = 32
+
+	lfgActionPath = "/api/v1/lfg/action"
+)
+
+// Looking-for-group button actions.
+const (
+	LFGActionJoin  = "join"
+	LFGActionLeave = "leave"
+)
+
+// LFG is a request in a channel for players to join a game.
+type LFG struct {
+	ID        string `json:"id"`
+	ChannelID string `json:"channel_id"`
+	PostID    string `json:"post_id"`
+	CreatorID string `json:"creator_id"`
+	AppID     int64  `json:"app_id"`
+	GameName  string `json:"game_name"`
+	Slots     int    `json:"slots"`
+	Note      string `json:"note"`
+	CreatedAt int64  `json:"created_at"`
+
+	// PlayerIDs are the users that joined, in the order they joined.
+	PlayerIDs []string `json:"player_ids"`
+}
+
+// IsFull returns true when every slot has been taken.
+func (l *LFG) IsFull() bool {
+	return len(l.PlayerIDs) >= l.Slots
+}
+
+// HasPlayer returns true if the user has joined.
+func (l *LFG) HasPlayer(userID string) bool {
+	for _, playerID := range l.PlayerIDs {
+		if playerID == userID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Join adds a user to the group.
+func (l *LFG) Join(userID string) error {
+	if userID == l.CreatorID || l.HasPlayer(userID) {
+		return errors.New("you have already joined this group")
+	}
+	if l.IsFull() {
+		return errors.New("this group is already full")
+	}
+	l.PlayerIDs = append(l.PlayerIDs, userID)
+
+	return nil
+}
+
+// Leave removes a user from the group.
+func (l *LFG) Leave(userID string) error {
+	for i, playerID := range l.PlayerIDs {
+		if playerID == userID {
+			l.PlayerIDs = append(l.PlayerIDs[:i], l.PlayerIDs[i+1:]...)
+			return nil
+		}
+	}
+
+	return errors.New("you haven't joined this group")
+}
+
+// parseLFGArgs splits looking-for-group arguments into the game, slots and
+// note. Slots are set with --slots and everything after --note is the note,
+// so that numbers in game names aren't taken as slots.
+func parseLFGArgs(args []string) (string, int, string, error) {
+	slots := lfgDefaultSlots
+	var gameArgs []string
+	var note string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--slots":
+			if i+1 >= len(args) {
+				return "", 0, "", errors.New("--slots requires a number")
+			}
+			var err error
+			slots, err = strconv.Atoi(args[i+1])
+			if err != nil || slots < 1 || slots > lfgMaxSlots {
+				return "", 0, "", fmt.Errorf("slots must be between 1 and %d", lfgMaxSlots)
+			}
+			i++
+		case "--note":
+			note = strings.Join(args[i+1:], " ")
+			i = len(args)
+		default:
+			gameArgs = append(gameArgs, args[i])
+		}
+	}
+	if len(gameArgs) == 0 {
+		return "", 0, "", errors.New("you must provide a game name or app ID")
+	}
+
+	return strings.Join(gameArgs, " "), slots, note, nil
+}
+
+func lfgKey(lfgID string) string {
+	return LFGKey + lfgID
+}
+
+func (p *Plugin) getLFG(lfgID string) (*LFG, []byte, error) {
+	lfgBytes, appErr := p.API.KVGet(lfgKey(lfgID))
+	if appErr != nil {
+		return nil, nil, errors.Wrap(appErr, "unable to get group")
+	}
+	if lfgBytes == nil {
+		return nil, nil, errors.New("group not found")
+	}
+
+	var lfg LFG
+	err := json.Unmarshal(lfgBytes, &lfg)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to parse group")
+	}
+
+	return &lfg, lfgBytes, nil
+}
+
+// storeLFG stores a looking-for-group post until it can no longer be joined.
+// If oldBytes is provided, it is only stored if it hasn't changed since it was
+// read.
+func (p *Plugin) storeLFG(lfg *LFG, oldBytes []byte) (bool, error) {
+	lfgBytes, err := json.Marshal(lfg)
+	if err != nil {
+		return false, errors.Wrap(err, "unable to marshal group")
+	}
+
+	// Updates keep the expiry set when the group was created.
+	if oldBytes != nil {
+		stored, appErr := p.API.KVCompareAndSet(lfgKey(lfg.ID), oldBytes, lfgBytes)
+		if appErr != nil {
+			return false, errors.Wrap(appErr, "unable to store group in database")
+		}
+		return stored, nil
+	}
+
+	expiry := int64(time.Until(time.Unix(lfg.CreatedAt, 0).Add(lfgRetention)).Seconds())
+	if expiry < 1 {
+		expiry = 1
+	}
+	appErr := p.API.KVSetWithExpiry(lfgKey(lfg.ID), lfgBytes, expiry)
+	if appErr != nil {
+		return false, errors.Wrap(appErr, "unable to store group in database")
+	}
+
+	return true, nil
+}
+
+// updateLFG applies a join or leave action, retrying if the group is updated
+// concurrently. Actions that aren't allowed return a message to show the
+// user.
+func (p *Plugin) updateLFG(lfgID, userID, action string) (*LFG, string, error) {
+	for i := 0; i < StoreSteamRetries; i++ {
+		lfg, lfgBytes, err := p.getLFG(lfgID)
+		if err != nil {
+			return nil, "", err
+		}
+
+		switch action {
+		case LFGActionJoin:
+			// Only joining requires channel membership, so that players who
+			// left the channel can still free their slot.
+			if i == 0 {
+				if _, appErr := p.API.GetChannelMember(lfg.ChannelID, userID); appErr != nil {
+					return nil, "Unable to join: you must be a member of this channel.", nil
+				}
+			}
+			err = lfg.Join(userID)
+		case LFGActionLeave:
+			err = lfg.Leave(userID)
+		default:
+			return nil, "", fmt.Errorf("unknown action %s", action)
+		}
+		if err != nil {
+			return nil, fmt.Sprintf("Unable to %s: %s.", action, err.Error()), nil
+		}
+
+		stored, err := p.storeLFG(lfg, lfgBytes)
+		if err != nil {
+			return nil, "", err
+		}
+		if stored {
+			return lfg, "", nil
+		}
+	}
+
+	return nil, "", errors.New("unable to update group after several attempts")
+}
+
+// makeLFGPost returns the interactive card for a looking-for-group post.
+func (p *Plugin) makeLFGPost(lfg *LFG) *model.Post {
+	game := Game{AppID: lfg.AppID, Name: lfg.GameName}
+
+	text := fmt.Sprintf("%s is looking for %d players", p.getUserMention(lfg.CreatorID), lfg.Slots)
+	if lfg.Note != "" {
+		text += fmt.Sprintf(": %s", lfg.Note)
+	}
+	text += "\n"
+
+	var players []string
+	for _, playerID := range lfg.PlayerIDs {
+		players = append(players, p.getUserMention(playerID))
+	}
+	if len(players) == 0 {
+		players = []string{"-"}
+	}
+	text += fmt.Sprintf("Players (%d/%d): %s\n", len(lfg.PlayerIDs), lfg.Slots, strings.Join(players, ", "))
+	text += fmt.Sprintf("[Launch %s](steam://rungameid/%d)", game.Name, game.AppID)
+
+	title := fmt.Sprintf("LFG: %s", game.Name)
+	if lfg.IsFull() {
+		title += " [Full]"
+	}
+
+	actionURL := p.getPluginURL(lfgActionPath)
+	makeAction := func(name, action string) *model.PostAction {
+		return &model.PostAction{
+			Name: name,
+			Integration: &model.PostActionIntegration{
+				URL: actionURL,
+				Context: map[string]interface{}{
+					"lfg_id": lfg.ID,
+					"action": action,
+				},
+			},
+		}
+	}
+
+	var actions []*model.PostAction
+	if !lfg.IsFull() {
+		actions = append(actions, makeAction("Join", LFGActionJoin))
+	}
+	actions = append(actions, makeAction("Leave", LFGActionLeave))
+
+	post := &model.Post{
+		Id:        lfg.PostID,
+		UserId:    p.BotUserID,
+		ChannelId: lfg.ChannelID,
+	}
+	model.ParseSlackAttachment(post, []*model.SlackAttachment{{
+		Title:     title,
+		TitleLink: game.StoreLink(),
+		ThumbURL:  gameCapsuleImgURL(fmt.Sprintf("%d", game.AppID)),
+		Text:      text,
+		Actions:   actions,
+	}})
+
+	return post
+}
+
+// notifyLFG sends a DM to connected channel members who own the game and
+// opted in to looking-for-group notifications.
+func (p *Plugin) notifyLFG(lfg *LFG) error {
+	userInfos, err := p.getSteamUsers()
+	if err != nil {
+		return err
+	}
+
+	var userIDs []string
+	for _, userInfo := range userInfos {
+		if !userInfo.Settings.NotifyLFG || userInfo.MattermostUserID == lfg.CreatorID {
+			continue
+		}
+		if _, appErr := p.API.GetChannelMember(lfg.ChannelID, userInfo.MattermostUserID); appErr != nil {
+			continue
+		}
+		userIDs = append(userIDs, userInfo.MattermostUserID)
+	}
+
+	game := Game{AppID: lfg.AppID, Name: lfg.GameName}
+	message := fmt.Sprintf("%s is looking for players for [%s](%s). [Join them](%s)",
+		p.getUserMention(lfg.CreatorID), game.Name, game.StoreLink(), p.getPermalink(lfg.PostID))
+
+	for userID, gameMap := range p.getOwnedGamesForUsers(userIDs, accessSelf) {
+		if _, ok := gameMap[lfg.AppID]; !ok {
+			continue
+		}
+
+		err = p.PostBotDM(userID, message)
+		if err != nil {
+			p.API.LogError(errors.Wrapf(err, "unable to notify %s of group", userID).Error())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLFGArgs(t *testing.T) {
+	game, slots, note, err := parseLFGArgs([]string{"Deep", "Rock", "Galactic"})
+	require.NoError(t, err)
+	assert.Equal(t, "Deep Rock Galactic", game)
+	assert.Equal(t, lfgDefaultSlots, slots)
+	assert.Equal(t, "", note)
+
+	game, slots, note, err = parseLFGArgs([]string{"730", "--slots", "4", "--note", "ranked", "tonight"})
+	require.NoError(t, err)
+	assert.Equal(t, "730", game)
+	assert.Equal(t, 4, slots)
+	assert.Equal(t, "ranked tonight", note)
+
+	game, slots, note, err = parseLFGArgs([]string{"Left", "4", "Dead", "2", "--note", "--slots", "is", "literal"})
+	require.NoError(t, err)
+	assert.Equal(t, "Left 4 Dead 2", game)
+	assert.Equal(t, lfgDefaultSlots, slots)
+	assert.Equal(t, "--slots is literal", note)
+
+	_, _, _, err = parseLFGArgs([]string{"Portal", "--slots", "0"})
+	assert.Error(t, err)
+
+	_, _, _, err = parseLFGArgs([]string{"Portal", "--slots"})
+	assert.Error(t, err)
+
+	_, _, _, err = parseLFGArgs([]string{"--slots", "3"})
+	assert.Error(t, err)
+
+	_, _, _, err = parseLFGArgs(nil)
+	assert.Error(t, err)
+}
+
+func TestLFGJoinLeave(t *testing.T) {
+	lfg := &LFG{CreatorID: "creator", Slots: 2}
+
+	assert.Error(t, lfg.Join("creator"))
+	require.NoError(t, lfg.Join("user1"))
+	assert.Error(t, lfg.Join("user1"))
+	require.NoError(t, lfg.Join("user2"))
+	assert.True(t, lfg.IsFull())
+	assert.Error(t, lfg.Join("user3"))
+
+	require.NoError(t, lfg.Leave("user1"))
+	assert.False(t, lfg.IsFull())
+	assert.Error(t, lfg.Leave("user1"))
+	assert.Equal(t, []string{"user2"}, lfg.PlayerIDs)
+}
+
+func TestStoreLFG(t *testing.T) {
+	lfg := &LFG{ID: "lfg1", CreatedAt: time.Now().Unix()}
+
+	t.Run("create sets an expiry", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("KVSetWithExpiry", lfgKey("lfg1"), mock.Anything, mock.AnythingOfType("int64")).Return(nil)
+		defer api.AssertExpectations(t)
+
+		stored, err := newTestPlugin(api).storeLFG(lfg, nil)
+		require.NoError(t, err)
+		assert.True(t, stored)
+	})
+
+	t.Run("update only compares and sets", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("KVCompareAndSet", lfgKey("lfg1"), []byte("old"), mock.Anything).Return(true, nil)
+		defer api.AssertExpectations(t)
+
+		stored, err := newTestPlugin(api).storeLFG(lfg, []byte("old"))
+		require.NoError(t, err)
+		assert.True(t, stored)
+	})
+}
+
+func TestUpdateLFG(t *testing.T) {
+	lfgBytes, err := json.Marshal(&LFG{ID: "lfg1", ChannelID: "channel1", CreatorID: "creator", Slots: 3, PlayerIDs: []string{"user2"}})
+	require.NoError(t, err)
+
+	t.Run("non-members can't join", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("KVGet", lfgKey("lfg1")).Return(lfgBytes, nil)
+		api.On("GetChannelMember", "channel1", "user1").Return(nil, &model.AppError{Message: "not found"})
+		defer api.AssertExpectations(t)
+
+		lfg, message, err := newTestPlugin(api).updateLFG("lfg1", "user1", LFGActionJoin)
+		require.NoError(t, err)
+		assert.Nil(t, lfg)
+		assert.Equal(t, "Unable to join: you must be a member of this channel.", message)
+		api.AssertNotCalled(t, "KVCompareAndSet", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("members can join", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("KVGet", lfgKey("lfg1")).Return(lfgBytes, nil)
+		api.On("GetChannelMember", "channel1", "user1").Return(&model.ChannelMember{ChannelId: "channel1", UserId: "user1"}, nil)
+		api.On("KVCompareAndSet", lfgKey("lfg1"), lfgBytes, mock.Anything).Return(true, nil)
+		defer api.AssertExpectations(t)
+
+		lfg, message, err := newTestPlugin(api).updateLFG("lfg1", "user1", LFGActionJoin)
+		require.NoError(t, err)
+		assert.Empty(t, message)
+		assert.Equal(t, []string{"user2", "user1"}, lfg.PlayerIDs)
+	})
+
+	t.Run("players can leave without being members", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("KVGet", lfgKey("lfg1")).Return(lfgBytes, nil)
+		api.On("KVCompareAndSet", lfgKey("lfg1"), lfgBytes, mock.Anything).Return(true, nil)
+		defer api.AssertExpectations(t)
+
+		lfg, message, err := newTestPlugin(api).updateLFG("lfg1", "user2", LFGActionLeave)
+		require.NoError(t, err)
+		assert.Empty(t, message)
+		assert.Empty(t, lfg.PlayerIDs)
+	})
+}
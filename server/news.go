@@ -0,0 +1,335 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// NewsSubscriptionsKey is the store prefix for a channel's game news
+	// subscriptions.
+	NewsSubscriptionsKey = "news_subscriptions_"
+
+	steamAPIGetNewsForApp = "ISteamNews/GetNewsForApp/v0002"
+
+	// newsInterval is how often subscribed games are checked for news.
+	newsInterval = 30 * time.Minute
+
+	// newsItemsPerCheck is the number of recent news items requested per game.
+	newsItemsPerCheck = 10
+
+	// newsContentMaxLength is the number of characters of a news item's
+	// contents that are posted.
+	newsContentMaxLength = 500
+
+	// newsSeenGIDsMax is the number of news GIDs remembered per subscription
+	// to prevent posting the same item twice.
+	newsSeenGIDsMax = 100
+)
+
+var newsMarkupRegexp = regexp.MustCompile(`\[/?[a-zA-Z0-9*]+(=[^\]]*)?\]|<[^>]+>`)
+
+// NewsResponse is an API response for a game's news.
+type NewsResponse struct {
+	AppNews struct {
+		AppID     int64      `json:"appid"`
+		NewsItems []NewsItem `json:"newsitems"`
+	} `json:"appnews"`
+}
+
+// NewsItem is a single news post for a game.
+type NewsItem struct {
+	GID       string   `json:"gid"`
+	Title     string   `json:"title"`
+	URL       string   `json:"url"`
+	Author    string   `json:"author"`
+	Contents  string   `json:"contents"`
+	FeedLabel string   `json:"feedlabel"`
+	FeedName  string   `json:"feedname"`
+	Date      int64    `json:"date"`
+	Tags      []string `json:"tags"`
+}
+
+// NewsSubscription is a channel's subscription to a game's news.
+type NewsSubscription struct {
+	AppID     int64  `json:"app_id"`
+	Name      string `json:"name"`
+	CreatorID string `json:"creator_id"`
+
+	// Feeds limits posted news to the given feed names or labels. All feeds
+	// are posted when empty.
+	Feeds []string `json:"feeds,omitempty"`
+
+	// SeenGIDs are the most recent news items already posted or skipped.
+	SeenGIDs []string `json:"seen_gids,omitempty"`
+}
+
+// Description returns what the subscription is for in string form.
+func (s *NewsSubscription) Description() string {
+	game := Game{AppID: s.AppID, Name: s.Name}
+	description := fmt.Sprintf("[%s](%s)", game.Name, game.StoreLink())
+	if len(s.Feeds) > 0 {
+		description += fmt.Sprintf(" [feeds: %s]", strings.Join(s.Feeds, ", "))
+	}
+
+	return description
+}
+
+// MatchesFeed returns true if the news item is from one of the subscribed
+// feeds.
+func (s *NewsSubscription) MatchesFeed(item *NewsItem) bool {
+	if len(s.Feeds) == 0 {
+		return true
+	}
+
+	for _, feed := range s.Feeds {
+		if strings.EqualFold(feed, item.FeedName) || strings.EqualFold(feed, item.FeedLabel) {
+			return true
+		}
+		for _, tag := range item.Tags {
+			if strings.EqualFold(feed, tag) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// NewItems returns the news items that haven't been seen before and match the
+// subscribed feeds, oldest first. All items are marked as seen.
+func (s *NewsSubscription) NewItems(items []NewsItem) []NewsItem {
+	seen := make(map[string]bool)
+	for _, gid := range s.SeenGIDs {
+		seen[gid] = true
+	}
+
+	var newItems []NewsItem
+	for i := len(items) - 1; i >= 0; i-- {
+		item := items[i]
+		if seen[item.GID] {
+			continue
+		}
+		seen[item.GID] = true
+		s.SeenGIDs = append(s.SeenGIDs, item.GID)
+
+		if s.MatchesFeed(&item) {
+			newItems = append(newItems, item)
+		}
+	}
+
+	if len(s.SeenGIDs) > newsSeenGIDsMax {
+		s.SeenGIDs = s.SeenGIDs[len(s.SeenGIDs)-newsSeenGIDsMax:]
+	}
+
+	return newItems
+}
+
+// getGameNews returns the most recent news items for a game, newest first.
+func getGameNews(appID int64) ([]NewsItem, error) {
+	url := fmt.Sprintf("https://api.steampowered.com/%s/?appid=%d&count=%d&format=json", steamAPIGetNewsForApp, appID, newsItemsPerCheck)
+	result, err := steamAPICall(url)
+	if err != nil {
+		return nil, err
+	}
+
+	var response NewsResponse
+	err = json.Unmarshal(result, &response)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse game news")
+	}
+
+	return response.AppNews.NewsItems, nil
+}
+
+// truncateNewsContents strips markup from news contents and truncates them to
+// the given number of characters.
+func truncateNewsContents(contents string, maxLength int) string {
+	contents = newsMarkupRegexp.ReplaceAllString(contents, "")
+	contents = strings.Join(strings.Fields(contents), " ")
+
+	if utf8.RuneCountInString(contents) <= maxLength {
+		return contents
+	}
+
+	runes := []rune(contents)
+	truncated := strings.TrimSpace(string(runes[:maxLength]))
+	if i := strings.LastIndex(truncated, " "); i > maxLength/2 {
+		truncated = truncated[:i]
+	}
+
+	return truncated + "..."
+}
+
+func formatNewsItem(gameName string, item *NewsItem) string {
+	message := fmt.Sprintf("#### [%s](%s)\n", item.Title, item.URL)
+	message += fmt.Sprintf("_%s", gameName)
+	if item.FeedLabel != "" {
+		message += fmt.Sprintf(" - %s", item.FeedLabel)
+	}
+	message += "_\n\n"
+	if contents := truncateNewsContents(item.Contents, newsContentMaxLength); contents != "" {
+		message += fmt.Sprintf("> %s\n", contents)
+	}
+
+	return message
+}
+
+func newsSubscriptionsKey(channelID string) string {
+	return NewsSubscriptionsKey + channelID
+}
+
+func (p *Plugin) getNewsSubscriptions(channelID string) ([]*NewsSubscription, error) {
+	subscriptionsBytes, appErr := p.API.KVGet(newsSubscriptionsKey(channelID))
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "unable to get news subscriptions")
+	}
+	if subscriptionsBytes == nil {
+		return nil, nil
+	}
+
+	var subscriptions []*NewsSubscription
+	err := json.Unmarshal(subscriptionsBytes, &subscriptions)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse news subscriptions")
+	}
+
+	return subscriptions, nil
+}
+
+func (p *Plugin) storeNewsSubscriptions(channelID string, subscriptions []*NewsSubscription) error {
+	if len(subscriptions) == 0 {
+		appErr := p.API.KVDelete(newsSubscriptionsKey(channelID))
+		if appErr != nil {
+			return errors.Wrap(appErr, "unable to delete news subscriptions in database")
+		}
+		return nil
+	}
+
+	subscriptionsBytes, err := json.Marshal(subscriptions)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal news subscriptions")
+	}
+
+	appErr := p.API.KVSet(newsSubscriptionsKey(channelID), subscriptionsBytes)
+	if appErr != nil {
+		return errors.Wrap(appErr, "unable to store news subscriptions in database")
+	}
+
+	return nil
+}
+
+// getNewsSubscriptionChannelIDs returns the IDs of all channels with news
+// subscriptions.
+func (p *Plugin) getNewsSubscriptionChannelIDs() ([]string, error) {
+	keys, err := p.getAllKVKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	var channelIDs []string
+	for _, key := range keys {
+		if strings.HasPrefix(key, NewsSubscriptionsKey) {
+			channelIDs = append(channelIDs, strings.TrimPrefix(key, NewsSubscriptionsKey))
+		}
+	}
+
+	return channelIDs, nil
+}
+
+func newsJobInterval(config *configuration) time.Duration {
+	return newsInterval
+}
+
+// runNewsJob posts new news items for every channel news subscription.
+func (p *Plugin) runNewsJob() error {
+	channelIDs, err := p.getNewsSubscriptionChannelIDs()
+	if err != nil {
+		return err
+	}
+
+	// Several channels often follow the same game, so news is only fetched
+	// once per game.
+	news := make(map[int64][]NewsItem)
+	for _, channelID := range channelIDs {
+		err = p.postNewsForChannel(channelID, news)
+		if err != nil {
+			p.API.LogError(errors.Wrapf(err, "unable to post news for channel %s", channelID).Error())
+		}
+	}
+
+	return nil
+}
+
+func (p *Plugin) postNewsForChannel(channelID string, news map[int64][]NewsItem) error {
+	subscriptions, err := p.getNewsSubscriptions(channelID)
+	if err != nil {
+		return err
+	}
+
+	for _, subscription := range subscriptions {
+		items, ok := news[subscription.AppID]
+		if !ok {
+			items, err = getGameNews(subscription.AppID)
+			if err != nil {
+				p.API.LogError(errors.Wrapf(err, "unable to get news for app %d", subscription.AppID).Error())
+				continue
+			}
+			news[subscription.AppID] = items
+		}
+
+		for _, item := range subscription.NewItems(items) {
+			// A failed post is skipped rather than ending the run, so that the
+			// items already posted are still stored as seen.
+			err = p.PostToChannelByIDAsBot(channelID, formatNewsItem(subscription.Name, &item))
+			if err != nil {
+				p.API.LogError(errors.Wrapf(err, "unable to post news item %s", item.GID).Error())
+			}
+		}
+	}
+
+	// Subscriptions may have been added or removed while news was posted, so
+	// only the seen news is merged into the subscriptions as they are now.
+	current, err := p.getNewsSubscriptions(channelID)
+	if err != nil {
+		return err
+	}
+	if len(current) == 0 {
+		return nil
+	}
+	mergeNewsSubscriptionState(current, subscriptions)
+
+	return p.storeNewsSubscriptions(channelID, current)
+}
+
+// mergeNewsSubscriptionState adds the news seen by checked subscriptions to
+// the subscriptions for the same games in current.
+func mergeNewsSubscriptionState(current, checked []*NewsSubscription) {
+	for _, subscription := range current {
+		for _, checkedSubscription := range checked {
+			if subscription.AppID != checkedSubscription.AppID {
+				continue
+			}
+
+			seen := make(map[string]bool)
+			for _, gid := range subscription.SeenGIDs {
+				seen[gid] = true
+			}
+			for _, gid := range checkedSubscription.SeenGIDs {
+				if !seen[gid] {
+					subscription.SeenGIDs = append(subscription.SeenGIDs, gid)
+				}
+			}
+			if len(subscription.SeenGIDs) > newsSeenGIDsMax {
+				subscription.SeenGIDs = subscription.SeenGIDs[len(subscription.SeenGIDs)-newsSeenGIDsMax:]
+			}
+			break
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewsSubscriptionNewItems(t *testing.T) {
+	items := []NewsItem{
+		{GID: "3", FeedName: "steam_community_announcements"},
+		{GID: "2", FeedName: "pcgamer"},
+		{GID: "1", FeedName: "steam_community_announcements"},
+	}
+
+	t.Run("unseen items are returned oldest first", func(t *testing.T) {
+		subscription := &NewsSubscription{SeenGIDs: []string{"1"}}
+		newItems := subscription.NewItems(items)
+		assert.Len(t, newItems, 2)
+		assert.Equal(t, "2", newItems[0].GID)
+		assert.Equal(t, "3", newItems[1].GID)
+		assert.Empty(t, subscription.NewItems(items))
+	})
+
+	t.Run("feeds are filtered", func(t *testing.T) {
+		subscription := &NewsSubscription{Feeds: []string{"Steam_Community_Announcements"}}
+		newItems := subscription.NewItems(items)
+		assert.Len(t, newItems, 2)
+		assert.Equal(t, "1", newItems[0].GID)
+		assert.Equal(t, []string{"1", "2", "3"}, subscription.SeenGIDs)
+	})
+
+	t.Run("tags are matched", func(t *testing.T) {
+		subscription := &NewsSubscription{Feeds: []string{"patchnotes"}}
+		newItems := subscription.NewItems([]NewsItem{{GID: "1", Tags: []string{"patchnotes"}}, {GID: "2"}})
+		assert.Len(t, newItems, 1)
+		assert.Equal(t, "1", newItems[0].GID)
+	})
+}
+
+func TestTruncateNewsContents(t *testing.T) {
+	assert.Equal(t, "Patch notes fixed a bug", truncateNewsContents("[h1]Patch notes[/h1]\n[list][*]fixed <b>a</b> bug[/list]", 100))
+	assert.Equal(t, "one two...", truncateNewsContents("one two three four", 10))
+	assert.Equal(t, "", truncateNewsContents("", 10))
+}
+
+func TestMergeNewsSubscriptionState(t *testing.T) {
+	checked := []*NewsSubscription{
+		{AppID: 10, SeenGIDs: []string{"1", "2", "3"}},
+		{AppID: 20, SeenGIDs: []string{"4"}},
+	}
+	current := []*NewsSubscription{
+		{AppID: 10, SeenGIDs: []string{"1", "2"}},
+		{AppID: 30, SeenGIDs: []string{"5"}},
+	}
+
+	mergeNewsSubscriptionState(current, checked)
+	assert.Len(t, current, 2)
+	assert.Equal(t, []string{"1", "2", "3"}, current[0].SeenGIDs)
+	assert.Equal(t, []string{"5"}, current[1].SeenGIDs)
+}
+
+func TestPostNewsForChannelStoresSeenAfterPostFailure(t *testing.T) {
+	subscriptionsBytes, err := json.Marshal([]*NewsSubscription{{AppID: 10, Name: "Game"}})
+	require.NoError(t, err)
+	news := map[int64][]NewsItem{
+		10: {{GID: "2", Title: "Second"}, {GID: "1", Title: "First"}},
+	}
+
+	var stored []*NewsSubscription
+	api := &plugintest.API{}
+	api.On("KVGet", newsSubscriptionsKey("channel1")).Return(subscriptionsBytes, nil)
+	api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool { return post.Message == formatNewsItem("Game", &news[10][1]) })).Return(nil, &model.AppError{Message: "failed"})
+	api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool { return post.Message == formatNewsItem("Game", &news[10][0]) })).Return(&model.Post{}, nil)
+	api.On("LogError", mock.Anything).Return()
+	api.On("KVSet", newsSubscriptionsKey("channel1"), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		require.NoError(t, json.Unmarshal(args.Get(1).([]byte), &stored))
+	})
+	defer api.AssertExpectations(t)
+
+	require.NoError(t, newTestPlugin(api).postNewsForChannel("channel1", news))
+	require.Len(t, stored, 1)
+	assert.Equal(t, []string{"1", "2"}, stored[0].SeenGIDs)
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// PlaytimeSnapshotKey is the store prefix for a daily playtime snapshot.
+	PlaytimeSnapshotKey = "playtime_"
+
+	// playtimeSnapshotDateFormat is the date format of snapshots and of the
+	// dates given to --since and --until.
+	playtimeSnapshotDateFormat = "2006-01-02"
+
+	// playtimeSnapshotKeyDateFormat is the date format used in snapshot keys,
+	// kept short so that keys fit within the store's key length limit.
+	playtimeSnapshotKeyDateFormat = "20060102"
+
+	// playtimeSnapshotRetentionDays is how long daily snapshots are kept.
+	playtimeSnapshotRetentionDays = 400
+
+	// playtimeSnapshotLookbackDays is how far back to look for a snapshot
+	// when none exists for the requested day.
+	playtimeSnapshotLookbackDays = 7
+)
+
+// PlaytimeSnapshot is a user's owned-games playtime on a given day.
+type PlaytimeSnapshot struct {
+	Date  string                         `json:"date"`
+	Games map[int64]PlaytimeSnapshotGame `json:"games"`
+}
+
+// PlaytimeSnapshotGame is the playtime of a single game in a snapshot.
+type PlaytimeSnapshotGame struct {
+	Name            string `json:"name"`
+	Playtime        int64  `json:"playtime"`
+	WindowsPlaytime int64  `json:"windows_playtime"`
+	MacPlaytime     int64  `json:"mac_playtime"`
+	LinuxPlaytime   int64  `json:"linux_playtime"`
+}
+
+// playtimeWindow is a period of time to aggregate playtime over.
+type playtimeWindow struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Previous returns the window of equal length that ends when this one starts.
+func (w playtimeWindow) Previous() playtimeWindow {
+	return playtimeWindow{
+		Start: w.Start.Add(-w.End.Sub(w.Start)),
+		End:   w.Start,
+	}
+}
+
+func playtimeSnapshotKey(userID string, date time.Time) string {
+	return playtimeSnapshotKeyPrefix(userID) + date.UTC().Format(playtimeSnapshotKeyDateFormat)
+}
+
+// playtimeSnapshotKeyPrefix returns the prefix shared by all of a user's
+// snapshot keys.
+func playtimeSnapshotKeyPrefix(userID string) string {
+	return PlaytimeSnapshotKey + userID + "_"
+}
+
+// parsePlaytimeSnapshotKeyDate returns the day of the snapshot stored under a
+// key. False is returned if the key isn't a snapshot key.
+func parsePlaytimeSnapshotKeyDate(key string) (time.Time, bool) {
+	if !strings.HasPrefix(key, PlaytimeSnapshotKey) || len(key) < len(PlaytimeSnapshotKey)+len(playtimeSnapshotKeyDateFormat) {
+		return time.Time{}, false
+	}
+
+	date, err := time.Parse(playtimeSnapshotKeyDateFormat, key[len(key)-len(playtimeSnapshotKeyDateFormat):])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return date, true
+}
+
+// makePlaytimeSnapshot returns a snapshot of the provided owned games.
+func makePlaytimeSnapshot(date time.Time, gameMap map[int64]Game) *PlaytimeSnapshot {
+	snapshot := &PlaytimeSnapshot{
+		Date:  date.UTC().Format(playtimeSnapshotDateFormat),
+		Games: make(map[int64]PlaytimeSnapshotGame),
+	}
+	for appID, game := range gameMap {
+		snapshot.Games[appID] = PlaytimeSnapshotGame{
+			Name:            game.Name,
+			Playtime:        game.Playtime,
+			WindowsPlaytime: game.WindowsPlaytime,
+			MacPlaytime:     game.MacPlaytime,
+			LinuxPlaytime:   game.LinuxPlaytime,
+		}
+	}
+
+	return snapshot
+}
+
+func (p *Plugin) storePlaytimeSnapshot(userID string, date time.Time, snapshot *PlaytimeSnapshot) error {
+	jsonSnapshot, err := json.Marshal(snapshot)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal playtime snapshot")
+	}
+
+	expiry := date.AddDate(0, 0, playtimeSnapshotRetentionDays).Sub(time.Now())
+	if expiry <= 0 {
+		return nil
+	}
+
+	appErr := p.API.KVSetWithExpiry(playtimeSnapshotKey(userID, date), jsonSnapshot, int64(expiry.Seconds()))
+	if appErr != nil {
+		return errors.Wrap(appErr, "unable to store playtime snapshot in database")
+	}
+
+	return nil
+}
+
+// getPlaytimeSnapshot returns the user's snapshot for the given day, or nil
+// if there isn't one.
+func (p *Plugin) getPlaytimeSnapshot(userID string, date time.Time) (*PlaytimeSnapshot, error) {
+	return p.getPlaytimeSnapshotByKey(playtimeSnapshotKey(userID, date))
+}
+
+// getPlaytimeSnapshotByKey returns the snapshot stored under a key, or nil if
+// there isn't one.
+func (p *Plugin) getPlaytimeSnapshotByKey(key string) (*PlaytimeSnapshot, error) {
+	snapshotBytes, appErr := p.API.KVGet(key)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "unable to get playtime snapshot")
+	}
+	if snapshotBytes == nil {
+		return nil, nil
+	}
+
+	var snapshot PlaytimeSnapshot
+	err := json.Unmarshal(snapshotBytes, &snapshot)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse playtime snapshot")
+	}
+
+	return &snapshot, nil
+}
+
+// getPlaytimeSnapshotOnOrBefore returns the most recent snapshot taken on or
+// up to a week before the given day, or nil if there isn't one.
+func (p *Plugin) getPlaytimeSnapshotOnOrBefore(userID string, date time.Time) (*PlaytimeSnapshot, error) {
+	for i := 0; i <= playtimeSnapshotLookbackDays; i++ {
+		snapshot, err := p.getPlaytimeSnapshot(userID, date.AddDate(0, 0, -i))
+		if err != nil {
+			return nil, err
+		}
+		if snapshot != nil {
+			return snapshot, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// getPlaytimeDelta returns the minutes played per game by a user during a
+// window, limited to games their privacy settings allow to be read for the
+// purpose. False is returned if there isn't enough history for the window.
+func (p *Plugin) getPlaytimeDelta(userID string, window playtimeWindow, access steamDataAccess) (map[int64]int64, bool, error) {
+	userInfo, err := p.getSteamUserForAccess(userID, access)
+	if err != nil {
+		return nil, false, err
+	}
+
+	start, err := p.getPlaytimeSnapshotOnOrBefore(userID, window.Start)
+	if err != nil {
+		return nil, false, err
+	}
+	end, err := p.getPlaytimeSnapshotOnOrBefore(userID, window.End)
+	if err != nil {
+		return nil, false, err
+	}
+	if start == nil || end == nil || start.Date == end.Date {
+		return nil, false, nil
+	}
+
+	delta := playtimeSnapshotDelta(start, end)
+	for appID := range delta {
+		if !userInfo.Settings.AllowsGame(access, appID) {
+			delete(delta, appID)
+		}
+	}
+
+	return delta, true, nil
+}
+
+// playtimeSnapshotDelta returns the minutes played per game between two
+// snapshots. Games acquired after the start snapshot count from zero.
+func playtimeSnapshotDelta(start, end *PlaytimeSnapshot) map[int64]int64 {
+	delta := make(map[int64]int64)
+	for appID, game := range end.Games {
+		played := game.Playtime - start.Games[appID].Playtime
+		if played > 0 {
+			delta[appID] = played
+		}
+	}
+
+	return delta
+}
+
+// parsePlaytimeWindow parses --since and --until flags out of args, returning
+// the remaining args. A nil window is returned when no flags are present.
+//
+// --since accepts a relative duration in days, weeks or months (30d, 4w, 3m)
+// or a date. --until accepts a date and defaults to now.
+func parsePlaytimeWindow(args []string, now time.Time) (*playtimeWindow, []string, error) {
+	var since, until string
+	var remaining []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--since", "--until":
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("%s requires a value", args[i])
+			}
+			if args[i] == "--since" {
+				since = args[i+1]
+			} else {
+				until = args[i+1]
+			}
+			i++
+		default:
+			remaining = append(remaining, args[i])
+		}
+	}
+
+	if since == "" && until == "" {
+		return nil, remaining, nil
+	}
+	if since == "" {
+		return nil, nil, errors.New("--until requires --since")
+	}
+
+	window := &playtimeWindow{End: now.UTC()}
+	if until != "" {
+		end, err := time.Parse(playtimeSnapshotDateFormat, until)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s is not a valid date, must be YYYY-MM-DD", until)
+		}
+		window.End = end
+	}
+
+	start, err := parseSince(since, window.End)
+	if err != nil {
+		return nil, nil, err
+	}
+	window.Start = start
+
+	if !window.Start.Before(window.End) {
+		return nil, nil, errors.New("--since must be before --until")
+	}
+
+	return window, remaining, nil
+}
+
+// parseSince parses a relative duration or date into the start of a window
+// ending at end.
+func parseSince(since string, end time.Time) (time.Time, error) {
+	start, err := time.Parse(playtimeSnapshotDateFormat, since)
+	if err == nil {
+		return start, nil
+	}
+
+	invalid := fmt.Errorf("%s is not a valid duration or date, must be like 30d, 4w, 3m or YYYY-MM-DD", since)
+	if len(since) < 2 {
+		return time.Time{}, invalid
+	}
+	amount, err := strconv.Atoi(since[:len(since)-1])
+	if err != nil || amount <= 0 {
+		return time.Time{}, invalid
+	}
+
+	switch strings.ToLower(since[len(since)-1:]) {
+	case "d":
+		return end.AddDate(0, 0, -amount), nil
+	case "w":
+		return end.AddDate(0, 0, -7*amount), nil
+	case "m":
+		return end.AddDate(0, -amount, 0), nil
+	}
+
+	return time.Time{}, invalid
+}
+
+// runPlaytimeSnapshotJob stores a playtime snapshot for every connected user.
+// Snapshots expire on their own after retention, and snapshots stored before
+// they were given an expiry are removed here once past retention.
+func (p *Plugin) runPlaytimeSnapshotJob() error {
+	userIDs, err := p.getSteamUserIDs()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for userID, gameMap := range p.getOwnedGamesForUsers(userIDs, accessSelf) {
+		err = p.storePlaytimeSnapshot(userID, now, makePlaytimeSnapshot(now, gameMap))
+		if err != nil {
+			p.API.LogError(errors.Wrapf(err, "unable to store playtime snapshot for %s", userID).Error())
+		}
+	}
+
+	keys, err := p.getAllKVKeys()
+	if err != nil {
+		return err
+	}
+	cutoff := now.AddDate(0, 0, -playtimeSnapshotRetentionDays)
+	for _, key := range keys {
+		if !isExpiredPlaytimeSnapshotKey(key, cutoff) {
+			continue
+		}
+		appErr := p.API.KVDelete(key)
+		if appErr != nil {
+			p.API.LogError(errors.Wrapf(appErr, "unable to delete expired playtime snapshot %s", key).Error())
+		}
+	}
+
+	return nil
+}
+
+// isExpiredPlaytimeSnapshotKey returns true if the key is a playtime snapshot
+// from before the cutoff.
+func isExpiredPlaytimeSnapshotKey(key string, cutoff time.Time) bool {
+	date, ok := parsePlaytimeSnapshotKeyDate(key)
+
+	return ok && date.Before(cutoff)
+}
+
+func playtimeSnapshotJobInterval(config *configuration) time.Duration {
+	return 24 * time.Hour
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePlaytimeWindow(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	t.Run("no flags", func(t *testing.T) {
+		window, remaining, err := parsePlaytimeWindow([]string{"other"}, now)
+		require.NoError(t, err)
+		assert.Nil(t, window)
+		assert.Equal(t, []string{"other"}, remaining)
+	})
+
+	t.Run("relative", func(t *testing.T) {
+		window, remaining, err := parsePlaytimeWindow([]string{"--since", "30d"}, now)
+		require.NoError(t, err)
+		require.NotNil(t, window)
+		assert.Empty(t, remaining)
+		assert.Equal(t, now.AddDate(0, 0, -30), window.Start)
+		assert.Equal(t, now, window.End)
+
+		previous := window.Previous()
+		assert.Equal(t, now.AddDate(0, 0, -60), previous.Start)
+		assert.Equal(t, window.Start, previous.End)
+	})
+
+	t.Run("date range", func(t *testing.T) {
+		window, _, err := parsePlaytimeWindow([]string{"--since", "2026-01-01", "--until", "2026-04-01"}, now)
+		require.NoError(t, err)
+		require.NotNil(t, window)
+		assert.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), window.Start)
+		assert.Equal(t, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), window.End)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, args := range [][]string{
+			{"--since"},
+			{"--since", "30x"},
+			{"--since", "-3d"},
+			{"--until", "2026-01-01"},
+			{"--since", "2026-05-01", "--until", "2026-04-01"},
+		} {
+			_, _, err := parsePlaytimeWindow(args, now)
+			assert.Error(t, err, args)
+		}
+	})
+}
+
+func TestPlaytimeSnapshotDelta(t *testing.T) {
+	start := &PlaytimeSnapshot{Games: map[int64]PlaytimeSnapshotGame{
+		1: {Playtime: 100},
+		2: {Playtime: 50},
+	}}
+	end := &PlaytimeSnapshot{Games: map[int64]PlaytimeSnapshotGame{
+		1: {Playtime: 130},
+		2: {Playtime: 50},
+		3: {Playtime: 20},
+	}}
+
+	assert.Equal(t, map[int64]int64{1: 30, 3: 20}, playtimeSnapshotDelta(start, end))
+}
+
+func TestIsExpiredPlaytimeSnapshotKey(t *testing.T) {
+	cutoff := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.True(t, isExpiredPlaytimeSnapshotKey(playtimeSnapshotKey("user1", cutoff.AddDate(0, 0, -1)), cutoff))
+	assert.False(t, isExpiredPlaytimeSnapshotKey(playtimeSnapshotKey("user1", cutoff), cutoff))
+	assert.False(t, isExpiredPlaytimeSnapshotKey("user1_steam_user", cutoff))
+	assert.False(t, isExpiredPlaytimeSnapshotKey(PlaytimeSnapshotKey+"user1_invalid", cutoff))
+}
+
+func TestPlaytimeSnapshotKeyLength(t *testing.T) {
+	key := playtimeSnapshotKey(model.NewId(), time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC))
+	assert.True(t, utf8.RuneCountInString(key) <= model.KEY_VALUE_KEY_MAX_RUNES, "%s is longer than %d runes", key, model.KEY_VALUE_KEY_MAX_RUNES)
+}
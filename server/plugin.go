@@ -23,6 +23,11 @@ type Plugin struct {
 	// configuration is the active plugin configuration. Consult getConfiguration and
 	// setConfiguration for usage.
 	configuration *configuration
+
+	// stopJobs signals the background job loop to stop, and jobsDone is
+	// closed once it has.
+	stopJobs chan struct{}
+	jobsDone chan struct{}
 }
 
 // BuildHash is the full git hash of the build.
@@ -68,5 +73,19 @@ func (p *Plugin) OnActivate() error {
 		return errors.Wrap(appErr, "couldn't set profile image")
 	}
 
-	return p.API.RegisterCommand(getCommand())
+	err = p.API.RegisterCommand(getCommand())
+	if err != nil {
+		return errors.Wrap(err, "couldn't register command")
+	}
+
+	p.startBackgroundJobs()
+
+	return nil
+}
+
+// OnDeactivate runs when the plugin deactivates and stops background jobs.
+func (p *Plugin) OnDeactivate() error {
+	p.stopBackgroundJobs()
+
+	return nil
 }
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// PresenceStateKey is the store prefix for a user's tracked presence.
+	PresenceStateKey = "presence_state_"
+
+	// presencePollInterval is how often presence is polled.
+	presencePollInterval = 2 * time.Minute
+
+	// presenceMinSession is how long a game must be played before it is
+	// announced, so short sessions aren't announced.
+	presenceMinSession = 10 * time.Minute
+
+	// presenceAnnounceCooldown is how long before the same game is announced
+	// again for a user, so quitting and restarting a game isn't announced.
+	presenceAnnounceCooldown = time.Hour
+)
+
+// presenceState is the tracked presence of a user.
+type presenceState struct {
+	GameID              string `json:"game_id"`
+	GameName            string `json:"game_name"`
+	Since               int64  `json:"since"`
+	Announced           bool   `json:"announced"`
+	LastAnnouncedGameID string `json:"last_announced_game_id"`
+	LastAnnouncedAt     int64  `json:"last_announced_at"`
+}
+
+// Update records the game a user is currently playing, and returns true if
+// the game should now be announced. An empty gameID means not in game.
+func (s *presenceState) Update(gameID, gameName string, now time.Time) bool {
+	if gameID != s.GameID {
+		s.GameID = gameID
+		s.GameName = gameName
+		s.Since = now.Unix()
+		s.Announced = false
+	}
+
+	if s.GameID == "" || s.Announced {
+		return false
+	}
+	if now.Sub(time.Unix(s.Since, 0)) < presenceMinSession {
+		return false
+	}
+
+	// Consider the session announced even if skipped due to the cooldown so
+	// that it isn't checked again.
+	s.Announced = true
+	if s.LastAnnouncedGameID == s.GameID && now.Sub(time.Unix(s.LastAnnouncedAt, 0)) < presenceAnnounceCooldown {
+		s.LastAnnouncedAt = now.Unix()
+		return false
+	}
+	s.LastAnnouncedGameID = s.GameID
+	s.LastAnnouncedAt = now.Unix()
+
+	return true
+}
+
+func (p *Plugin) getPresenceState(userID string) (*presenceState, error) {
+	stateBytes, appErr := p.API.KVGet(PresenceStateKey + userID)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "unable to get presence state")
+	}
+
+	var state presenceState
+	if stateBytes == nil {
+		return &state, nil
+	}
+	err := json.Unmarshal(stateBytes, &state)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse presence state")
+	}
+
+	return &state, nil
+}
+
+func (p *Plugin) storePresenceState(userID string, state *presenceState) error {
+	stateBytes, err := json.Marshal(state)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal presence state")
+	}
+
+	appErr := p.API.KVSet(PresenceStateKey+userID, stateBytes)
+	if appErr != nil {
+		return errors.Wrap(appErr, "unable to store presence state in database")
+	}
+
+	return nil
+}
+
+// updatePresenceState records the game a user is currently playing, and
+// returns their presence state and whether the game should now be announced.
+// The state is only stored when it changed.
+func (p *Plugin) updatePresenceState(userID string, player Player, now time.Time) (*presenceState, bool, error) {
+	state, err := p.getPresenceState(userID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	previous := *state
+	announce := state.Update(player.GameID, player.GameExtraInfo, now)
+	if *state == previous {
+		return state, announce, nil
+	}
+
+	err = p.storePresenceState(userID, state)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return state, announce, nil
+}
+
+// getPlayerSummariesForUsers returns the current player summaries of the
+// provided users keyed by Mattermost user ID. Summaries are requested in
+// batches, each using the API key of a user in the batch.
+func (p *Plugin) getPlayerSummariesForUsers(userInfos []*SteamUserInfo) map[string]Player {
+	players := make(map[string]Player)
+
+	for start := 0; start < len(userInfos); start += playerSummariesMaxIDs {
+		end := start + playerSummariesMaxIDs
+		if end > len(userInfos) {
+			end = len(userInfos)
+		}
+		batch := userInfos[start:end]
+
+		steamIDToUserID := make(map[string]string)
+		var steamIDs []string
+		for _, userInfo := range batch {
+			steamIDToUserID[userInfo.SteamID] = userInfo.MattermostUserID
+			steamIDs = append(steamIDs, userInfo.SteamID)
+		}
+
+		summaries, err := getPlayerSummaries(batch[0].APIToken, steamIDs)
+		if err != nil {
+			p.API.LogError(errors.Wrap(err, "unable to get player summaries").Error())
+			continue
+		}
+
+		for _, player := range summaries {
+			if userID, ok := steamIDToUserID[player.SteamID]; ok {
+				players[userID] = player
+			}
+		}
+	}
+
+	return players
+}
+
+func presenceJobInterval(config *configuration) time.Duration {
+	if !config.PresenceAnnouncementsEnable {
+		return 0
+	}
+
+	return presencePollInterval
+}
+
+// runPresenceJob polls the current game of every user who opted in to
+// presence announcements and announces new sessions.
+func (p *Plugin) runPresenceJob() error {
+	config := p.getConfiguration()
+
+	userIDs, err := p.getSteamUserIDsForAccess(accessPresence)
+	if err != nil {
+		return err
+	}
+
+	var userInfos []*SteamUserInfo
+	settings := make(map[string]*UserSettings)
+	for _, userID := range userIDs {
+		userInfo, err := p.getSteamUserInfoByID(userID)
+		if err != nil {
+			p.API.LogError(errors.Wrapf(err, "unable to get steam user %s", userID).Error())
+			continue
+		}
+		userInfos = append(userInfos, userInfo)
+		settings[userID] = userInfo.Settings
+	}
+
+	now := time.Now()
+	for userID, player := range p.getPlayerSummariesForUsers(userInfos) {
+		state, announce, err := p.updatePresenceState(userID, player, now)
+		if err != nil {
+			p.API.LogError(err.Error())
+			continue
+		}
+		if !announce {
+			continue
+		}
+		if appID, err := strconv.ParseInt(state.GameID, 10, 64); err == nil && !settings[userID].AllowsGame(accessPresence, appID) {
+			continue
+		}
+
+		user, appErr := p.API.GetUser(userID)
+		if appErr != nil {
+			p.API.LogError(errors.Wrapf(appErr, "unable to get user %s", userID).Error())
+			continue
+		}
+
+		message := fmt.Sprintf("@%s started playing %s", user.Username, state.GameName)
+		appID, err := strconv.ParseInt(state.GameID, 10, 64)
+		if err == nil {
+			game := Game{AppID: appID}
+			message = fmt.Sprintf("@%s started playing [%s](%s)", user.Username, state.GameName, game.StoreLink())
+		}
+
+		err = p.PostToChannelByIDAsBot(config.PresenceChannelID, message)
+		if err != nil {
+			p.API.LogError(errors.Wrap(err, "unable to post presence announcement").Error())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPresenceStateUpdate(t *testing.T) {
+	start := time.Unix(1000000, 0)
+
+	t.Run("short sessions are not announced", func(t *testing.T) {
+		state := &presenceState{}
+		assert.False(t, state.Update("1", "Game", start))
+		assert.False(t, state.Update("1", "Game", start.Add(5*time.Minute)))
+		assert.False(t, state.Update("", "", start.Add(6*time.Minute)))
+		assert.False(t, state.Update("", "", start.Add(20*time.Minute)))
+	})
+
+	t.Run("announced once per session", func(t *testing.T) {
+		state := &presenceState{}
+		assert.False(t, state.Update("1", "Game", start))
+		assert.True(t, state.Update("1", "Game", start.Add(presenceMinSession)))
+		assert.False(t, state.Update("1", "Game", start.Add(2*presenceMinSession)))
+	})
+
+	t.Run("flapping is not announced again", func(t *testing.T) {
+		state := &presenceState{}
+		state.Update("1", "Game", start)
+		assert.True(t, state.Update("1", "Game", start.Add(presenceMinSession)))
+		state.Update("", "", start.Add(15*time.Minute))
+		state.Update("1", "Game", start.Add(16*time.Minute))
+		assert.False(t, state.Update("1", "Game", start.Add(30*time.Minute)))
+	})
+
+	t.Run("new game is announced", func(t *testing.T) {
+		state := &presenceState{}
+		state.Update("1", "Game", start)
+		assert.True(t, state.Update("1", "Game", start.Add(presenceMinSession)))
+		state.Update("2", "Other", start.Add(15*time.Minute))
+		assert.True(t, state.Update("2", "Other", start.Add(15*time.Minute+presenceMinSession)))
+	})
+}
+
+func TestUpdatePresenceState(t *testing.T) {
+	start := time.Unix(1000000, 0)
+	playing := Player{GameID: "1", GameExtraInfo: "Game"}
+
+	t.Run("unchanged state is not stored", func(t *testing.T) {
+		stateBytes, err := json.Marshal(&presenceState{GameID: "1", GameName: "Game", Since: start.Unix()})
+		require.NoError(t, err)
+
+		api := &plugintest.API{}
+		api.On("KVGet", PresenceStateKey+"user1").Return(stateBytes, nil)
+		defer api.AssertExpectations(t)
+
+		_, announce, err := newTestPlugin(api).updatePresenceState("user1", playing, start.Add(time.Minute))
+		require.NoError(t, err)
+		assert.False(t, announce)
+		api.AssertNotCalled(t, "KVSet", mock.Anything, mock.Anything)
+	})
+
+	t.Run("not in game is not stored", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("KVGet", PresenceStateKey+"user1").Return(nil, nil)
+		defer api.AssertExpectations(t)
+
+		_, _, err := newTestPlugin(api).updatePresenceState("user1", Player{}, start)
+		require.NoError(t, err)
+		api.AssertNotCalled(t, "KVSet", mock.Anything, mock.Anything)
+	})
+
+	t.Run("changed state is stored", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("KVGet", PresenceStateKey+"user1").Return(nil, nil)
+		api.On("KVSet", PresenceStateKey+"user1", mock.Anything).Return(nil)
+		defer api.AssertExpectations(t)
+
+		state, announce, err := newTestPlugin(api).updatePresenceState("user1", playing, start)
+		require.NoError(t, err)
+		assert.False(t, announce)
+		assert.Equal(t, "1", state.GameID)
+	})
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// PriceAlertsKey is the store prefix for a user's price alert
+	// subscriptions.
+	PriceAlertsKey = "price_alerts_"
+
+	// priceAlertsInterval is how often prices are checked.
+	priceAlertsInterval = 6 * time.Hour
+
+	// priceAlertsBatchSize is the number of games whose prices are requested
+	// at once.
+	priceAlertsBatchSize = 50
+)
+
+// PriceAlert is a subscription to price drops for a game or a user's whole
+// wishlist.
+type PriceAlert struct {
+	AppID    int64  `json:"app_id,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Wishlist bool   `json:"wishlist,omitempty"`
+
+	// TargetDiscount is the minimum discount percentage to alert at.
+	TargetDiscount int `json:"target_discount,omitempty"`
+
+	// TargetPrice is the maximum price, in the currency's smallest unit, to
+	// alert at.
+	TargetPrice int64 `json:"target_price,omitempty"`
+
+	// LastAlerted is the price each game was last alerted at, so the same
+	// price isn't alerted repeatedly.
+	LastAlerted map[int64]int64 `json:"last_alerted,omitempty"`
+}
+
+// Description returns what the alert is for in string form.
+func (a *PriceAlert) Description() string {
+	subject := "your wishlist"
+	if !a.Wishlist {
+		game := Game{AppID: a.AppID, Name: a.Name}
+		subject = fmt.Sprintf("[%s](%s)", game.Name, game.StoreLink())
+	}
+
+	switch {
+	case a.TargetPrice > 0:
+		return fmt.Sprintf("%s at or below %d.%02d", subject, a.TargetPrice/100, a.TargetPrice%100)
+	case a.TargetDiscount > 0:
+		return fmt.Sprintf("%s at %d%% off or more", subject, a.TargetDiscount)
+	}
+
+	return fmt.Sprintf("%s on any sale", subject)
+}
+
+// Matches returns true if the price meets the alert's target.
+func (a *PriceAlert) Matches(price *GamePrice) bool {
+	switch {
+	case a.TargetPrice > 0:
+		return price.Final <= a.TargetPrice
+	case a.TargetDiscount > 0:
+		return price.DiscountPercent >= a.TargetDiscount
+	}
+
+	return price.DiscountPercent > 0
+}
+
+// ShouldAlert returns true if the price meets the alert's target and hasn't
+// already been alerted for the game. Prices that no longer match are
+// forgotten so the next sale is alerted again.
+func (a *PriceAlert) ShouldAlert(appID int64, price *GamePrice) bool {
+	if a.LastAlerted == nil {
+		a.LastAlerted = make(map[int64]int64)
+	}
+
+	if !a.Matches(price) {
+		delete(a.LastAlerted, appID)
+		return false
+	}
+
+	if lastPrice, ok := a.LastAlerted[appID]; ok && lastPrice == price.Final {
+		return false
+	}
+	a.LastAlerted[appID] = price.Final
+
+	return true
+}
+
+// IsSameSubscription returns true if both alerts are for the same game or
+// wishlist with the same targets.
+func (a *PriceAlert) IsSameSubscription(other *PriceAlert) bool {
+	return a.Wishlist == other.Wishlist &&
+		a.AppID == other.AppID &&
+		a.TargetDiscount == other.TargetDiscount &&
+		a.TargetPrice == other.TargetPrice
+}
+
+// mergePriceAlertState copies the alerted prices of checked alerts to the
+// matching alerts in current. Alerts added since the check keep their state
+// and alerts removed since the check stay removed.
+func mergePriceAlertState(current, checked []*PriceAlert) {
+	for _, alert := range current {
+		for _, checkedAlert := range checked {
+			if alert.IsSameSubscription(checkedAlert) {
+				alert.LastAlerted = checkedAlert.LastAlerted
+				break
+			}
+		}
+	}
+}
+
+// formatPriceAlert returns the DM sent when a game's price meets an alert.
+func formatPriceAlert(game *Game, price *GamePrice) string {
+	message := fmt.Sprintf("Price alert: [%s](%s) is now %s", game.Name, game.StoreLink(), price.FinalFormatted)
+	if price.Currency != "" {
+		message += " " + price.Currency
+	}
+	if price.DiscountPercent > 0 {
+		message += fmt.Sprintf(" (-%d%%, was %s)", price.DiscountPercent, price.InitialFormatted)
+	}
+
+	return message
+}
+
+func (p *Plugin) getPriceAlerts(userID string) ([]*PriceAlert, error) {
+	alertsBytes, appErr := p.API.KVGet(PriceAlertsKey + userID)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "unable to get price alerts")
+	}
+	if alertsBytes == nil {
+		return nil, nil
+	}
+
+	var alerts []*PriceAlert
+	err := json.Unmarshal(alertsBytes, &alerts)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse price alerts")
+	}
+
+	return alerts, nil
+}
+
+func (p *Plugin) storePriceAlerts(userID string, alerts []*PriceAlert) error {
+	if len(alerts) == 0 {
+		appErr := p.API.KVDelete(PriceAlertsKey + userID)
+		if appErr != nil {
+			return errors.Wrap(appErr, "unable to delete price alerts in database")
+		}
+		return nil
+	}
+
+	alertsBytes, err := json.Marshal(alerts)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal price alerts")
+	}
+
+	appErr := p.API.KVSet(PriceAlertsKey+userID, alertsBytes)
+	if appErr != nil {
+		return errors.Wrap(appErr, "unable to store price alerts in database")
+	}
+
+	return nil
+}
+
+func priceAlertsJobInterval(config *configuration) time.Duration {
+	if !config.PriceAlertsEnable {
+		return 0
+	}
+
+	return priceAlertsInterval
+}
+
+// runPriceAlertsJob checks the prices of every subscribed game and sends a
+// DM for each alert whose target is met.
+func (p *Plugin) runPriceAlertsJob() error {
+	config := p.getConfiguration()
+
+	userIDs, err := p.getSteamUserIDs()
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		err = p.checkPriceAlertsForUser(userID, config.getPriceAlertCountryCode())
+		if err != nil {
+			p.API.LogError(errors.Wrapf(err, "unable to check price alerts for %s", userID).Error())
+		}
+	}
+
+	return nil
+}
+
+func (p *Plugin) checkPriceAlertsForUser(userID, countryCode string) error {
+	alerts, err := p.getPriceAlerts(userID)
+	if err != nil {
+		return err
+	}
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	alertAppIDs := make(map[*PriceAlert][]int64)
+	var appIDs []int64
+	for _, alert := range alerts {
+		if !alert.Wishlist {
+			alertAppIDs[alert] = []int64{alert.AppID}
+			appIDs = append(appIDs, alert.AppID)
+			continue
+		}
+
+		items, err := p.getWishlistForUser(userID, accessSelf)
+		if err != nil {
+			p.API.LogError(errors.Wrapf(err, "unable to get wishlist for %s", userID).Error())
+			continue
+		}
+		for _, item := range items {
+			alertAppIDs[alert] = append(alertAppIDs[alert], item.AppID)
+			appIDs = append(appIDs, item.AppID)
+		}
+	}
+
+	prices := make(map[int64]*GamePrice)
+	for start := 0; start < len(appIDs); start += priceAlertsBatchSize {
+		end := start + priceAlertsBatchSize
+		if end > len(appIDs) {
+			end = len(appIDs)
+		}
+
+		batchPrices, err := GetStorePrices(appIDs[start:end], countryCode)
+		if err != nil {
+			return err
+		}
+		for appID, price := range batchPrices {
+			prices[appID] = price
+		}
+	}
+
+	for _, alert := range alerts {
+		for _, appID := range alertAppIDs[alert] {
+			price, ok := prices[appID]
+			if !ok || !alert.ShouldAlert(appID, price) {
+				continue
+			}
+
+			game := Game{AppID: appID, Name: alert.Name}
+			if alert.Wishlist || game.Name == "" {
+				err = game.PopulateStoreData()
+				if err == nil {
+					game.Name = game.StoreData.Name
+				}
+			}
+
+			err = p.PostBotDM(userID, formatPriceAlert(&game, price))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Alerts may have been added or removed while prices were checked, so
+	// only the alerted prices are merged into the alerts as they are now.
+	current, err := p.getPriceAlerts(userID)
+	if err != nil {
+		return err
+	}
+	if len(current) == 0 {
+		return nil
+	}
+	mergePriceAlertState(current, alerts)
+
+	return p.storePriceAlerts(userID, current)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriceAlertMatches(t *testing.T) {
+	fullPrice := &GamePrice{Initial: 2000, Final: 2000}
+	onSale := &GamePrice{Initial: 2000, Final: 1500, DiscountPercent: 25}
+
+	anySale := &PriceAlert{AppID: 10}
+	assert.False(t, anySale.Matches(fullPrice))
+	assert.True(t, anySale.Matches(onSale))
+
+	discount := &PriceAlert{AppID: 10, TargetDiscount: 50}
+	assert.False(t, discount.Matches(onSale))
+	assert.True(t, discount.Matches(&GamePrice{Final: 1000, DiscountPercent: 50}))
+
+	price := &PriceAlert{AppID: 10, TargetPrice: 1500}
+	assert.False(t, price.Matches(fullPrice))
+	assert.True(t, price.Matches(onSale))
+}
+
+func TestPriceAlertShouldAlert(t *testing.T) {
+	alert := &PriceAlert{AppID: 10}
+	onSale := &GamePrice{Final: 1500, DiscountPercent: 25}
+
+	assert.True(t, alert.ShouldAlert(10, onSale))
+	assert.Equal(t, map[int64]int64{10: 1500}, alert.LastAlerted)
+	assert.False(t, alert.ShouldAlert(10, onSale), "the same price is only alerted once")
+
+	assert.True(t, alert.ShouldAlert(10, &GamePrice{Final: 1000, DiscountPercent: 50}), "a new sale price is alerted")
+
+	assert.False(t, alert.ShouldAlert(10, &GamePrice{Final: 2000}))
+	assert.Empty(t, alert.LastAlerted, "prices that no longer match are forgotten")
+	assert.True(t, alert.ShouldAlert(10, &GamePrice{Final: 1000, DiscountPercent: 50}))
+}
+
+func TestMergePriceAlertState(t *testing.T) {
+	checked := []*PriceAlert{
+		{AppID: 10, LastAlerted: map[int64]int64{10: 1500}},
+		{Wishlist: true, LastAlerted: map[int64]int64{20: 999}},
+		{AppID: 30, TargetDiscount: 50, LastAlerted: map[int64]int64{30: 500}},
+	}
+	current := []*PriceAlert{
+		{AppID: 10},
+		{AppID: 30, TargetDiscount: 75},
+		{AppID: 40, LastAlerted: map[int64]int64{40: 100}},
+	}
+
+	mergePriceAlertState(current, checked)
+	assert.Equal(t, map[int64]int64{10: 1500}, current[0].LastAlerted)
+	assert.Nil(t, current[1].LastAlerted, "changed targets start over")
+	assert.Equal(t, map[int64]int64{40: 100}, current[2].LastAlerted)
+	assert.Len(t, current, 3)
+}
+
+func TestFormatPriceAlert(t *testing.T) {
+	game := &Game{AppID: 10, Name: "Counter-Strike"}
+
+	assert.Equal(t, "Price alert: [Counter-Strike](https://store.steampowered.com/app/10) is now 4,99€ EUR (-50%, was 9,99€)",
+		formatPriceAlert(game, &GamePrice{Currency: "EUR", DiscountPercent: 50, InitialFormatted: "9,99€", FinalFormatted: "4,99€"}))
+	assert.Equal(t, "Price alert: [Counter-Strike](https://store.steampowered.com/app/10) is now $4.99",
+		formatPriceAlert(game, &GamePrice{FinalFormatted: "$4.99"}))
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"github.com/pkg/errors"
+)
+
+// steamDataAccess is the purpose for which a user's Steam data is read. Reads
+// of Steam data go through a purpose so that privacy settings are enforced in
+// one place rather than in each command.
+type steamDataAccess int
+
+const (
+	// accessSelf is a user reading their own data for themselves.
+	accessSelf steamDataAccess = iota
+
+	// accessProfile is showing a user's Steam profile to others.
+	accessProfile
+
+	// accessLibrary is showing a user's games to others, such as when
+	// comparing or looking up who owns a game.
+	accessLibrary
+
+	// accessStats is including a user's playtime in aggregate stats such as
+	// recent games, leaderboards and server wrapped reports.
+	accessStats
+
+	// accessPresence is announcing the game a user started playing.
+	accessPresence
+
+	// accessAchievements is announcing a user's achievement unlocks.
+	accessAchievements
+)
+
+// errSteamDataPrivate is returned when a user's privacy settings don't allow
+// their data to be read for a purpose.
+var errSteamDataPrivate = errors.New("steam data is private")
+
+// Allows returns true if the settings allow data to be read for the purpose.
+func (s *UserSettings) Allows(access steamDataAccess) bool {
+	switch access {
+	case accessSelf:
+		return true
+	case accessProfile:
+		return s.ShowProfile
+	case accessLibrary:
+		return !s.HideLibrary
+	case accessStats:
+		return !s.HideFromStats
+	case accessPresence:
+		return s.AnnouncePresence
+	case accessAchievements:
+		return s.AnnounceAchievements
+	}
+
+	return false
+}
+
+// IsGameHidden returns true if the game is on the user's hidden games list.
+func (s *UserSettings) IsGameHidden(appID int64) bool {
+	for _, hiddenAppID := range s.HiddenGames {
+		if hiddenAppID == appID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AllowsGame returns true if data about the game can be read for the purpose.
+// Hidden games are only visible to the user themselves.
+func (s *UserSettings) AllowsGame(access steamDataAccess, appID int64) bool {
+	return s.Allows(access) && (access == accessSelf || !s.IsGameHidden(appID))
+}
+
+// filterGameMap returns the games in gameMap that can be read for the
+// purpose.
+func (s *UserSettings) filterGameMap(access steamDataAccess, gameMap map[int64]Game) map[int64]Game {
+	if access == accessSelf || len(s.HiddenGames) == 0 {
+		return gameMap
+	}
+
+	filtered := make(map[int64]Game)
+	for appID, game := range gameMap {
+		if !s.IsGameHidden(appID) {
+			filtered[appID] = game
+		}
+	}
+
+	return filtered
+}
+
+// getSteamUserForAccess returns a user's Steam information if their privacy
+// settings allow their data to be read for the purpose, and
+// errSteamDataPrivate otherwise.
+func (p *Plugin) getSteamUserForAccess(userID string, access steamDataAccess) (*SteamUserInfo, error) {
+	userInfo, err := p.getSteamUserInfoByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !userInfo.Settings.Allows(access) {
+		return nil, errSteamDataPrivate
+	}
+
+	return userInfo, nil
+}
+
+// getSteamUserIDsForAccess returns the IDs of connected users whose privacy
+// settings allow their data to be read for the purpose.
+func (p *Plugin) getSteamUserIDsForAccess(access steamDataAccess) ([]string, error) {
+	userInfos, err := p.getSteamUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	var userIDs []string
+	for _, userInfo := range userInfos {
+		if userInfo.Settings.Allows(access) {
+			userIDs = append(userIDs, userInfo.MattermostUserID)
+		}
+	}
+
+	return userIDs, nil
+}
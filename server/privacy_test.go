@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserSettingsAllows(t *testing.T) {
+	settings := &UserSettings{}
+
+	assert.True(t, settings.Allows(accessSelf))
+	assert.False(t, settings.Allows(accessProfile))
+	assert.True(t, settings.Allows(accessLibrary))
+	assert.True(t, settings.Allows(accessStats))
+	assert.False(t, settings.Allows(accessPresence))
+	assert.False(t, settings.Allows(accessAchievements))
+
+	settings.HideLibrary = true
+	settings.HideFromStats = true
+	assert.True(t, settings.Allows(accessSelf))
+	assert.False(t, settings.Allows(accessLibrary))
+	assert.False(t, settings.Allows(accessStats))
+}
+
+func TestUserSettingsHiddenGames(t *testing.T) {
+	settings := &UserSettings{HiddenGames: []int64{20}}
+	gameMap := map[int64]Game{
+		10: {AppID: 10, Name: "Portal"},
+		20: {AppID: 20, Name: "Team Fortress Classic"},
+	}
+
+	assert.True(t, settings.AllowsGame(accessSelf, 20))
+	assert.False(t, settings.AllowsGame(accessLibrary, 20))
+	assert.True(t, settings.AllowsGame(accessLibrary, 10))
+
+	assert.Len(t, settings.filterGameMap(accessSelf, gameMap), 2)
+	filtered := settings.filterGameMap(accessStats, gameMap)
+	assert.Len(t, filtered, 1)
+	assert.Contains(t, filtered, int64(10))
+}
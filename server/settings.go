@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	settingsDialogPath       = "/api/v1/settings/dialog"
+	settingsDialogCallbackID = "steam_settings"
+)
+
+// userSettingType is the type of value a user setting holds.
+type userSettingType string
+
+// User setting types.
+const (
+	userSettingTypeBool userSettingType = "bool"
+)
+
+// userSetting describes a user setting that can be viewed and changed with
+// /steam settings and the settings dialog.
+type userSetting struct {
+	Name          string
+	Aliases       []string
+	Type          userSettingType
+	AllowedValues []string
+	Default       string
+	Description   string
+
+	get func(settings *UserSettings) string
+	set func(settings *UserSettings, value string)
+}
+
+// newBoolUserSetting returns a setting backed by a boolean field of
+// UserSettings.
+func newBoolUserSetting(name string, defaultValue bool, description string, field func(settings *UserSettings) *bool) *userSetting {
+	return &userSetting{
+		Name:          name,
+		Type:          userSettingTypeBool,
+		AllowedValues: []string{"true", "false"},
+		Default:       strconv.FormatBool(defaultValue),
+		Description:   description,
+		get: func(settings *UserSettings) string {
+			return strconv.FormatBool(*field(settings))
+		},
+		set: func(settings *UserSettings, value string) {
+			*field(settings) = value == "true"
+		},
+	}
+}
+
+// userSettings is the registry of user settings, in the order they are
+// displayed.
+var userSettings = []*userSetting{
+	newBoolUserSetting("show-profile", false, "Show your Steam profile in your Mattermost profile",
+		func(s *UserSettings) *bool { return &s.ShowProfile }),
+	newBoolUserSetting("show-activity", false, "Show your online state and recent games in your profile",
+		func(s *UserSettings) *bool { return &s.ShowActivity }),
+	newBoolUserSetting("hide-library", false, "Hide your games from compare, play, wishlist and game lookups",
+		func(s *UserSettings) *bool { return &s.HideLibrary }),
+	// hide-from-leaderboards was the name of hide-from-stats before it also
+	// covered other aggregate stats.
+	newBoolUserSetting("hide-from-stats", false, "Leave your playtime out of recent games, leaderboards and server wrapped reports",
+		func(s *UserSettings) *bool { return &s.HideFromStats }).withAliases("hide-from-leaderboards"),
+	newBoolUserSetting("announce-presence", false, "Announce when you start playing a game",
+		func(s *UserSettings) *bool { return &s.AnnouncePresence }),
+	newBoolUserSetting("announce-achievements", false, "Announce your achievement unlocks",
+		func(s *UserSettings) *bool { return &s.AnnounceAchievements }),
+	newBoolUserSetting("notify-lfg", false, "Get a DM when someone is looking for players for a game you own",
+		func(s *UserSettings) *bool { return &s.NotifyLFG }),
+}
+
+// withAliases adds other names the setting can be changed with.
+func (s *userSetting) withAliases(aliases ...string) *userSetting {
+	s.Aliases = append(s.Aliases, aliases...)
+	return s
+}
+
+// getUserSetting returns the setting with the given name or alias, or nil if
+// there is no such setting.
+func getUserSetting(name string) *userSetting {
+	for _, setting := range userSettings {
+		if setting.Name == name {
+			return setting
+		}
+		for _, alias := range setting.Aliases {
+			if alias == name {
+				return setting
+			}
+		}
+	}
+
+	return nil
+}
+
+// getUserSettingNames returns the names of all settings, quoted for use in
+// messages.
+func getUserSettingNames() string {
+	var names []string
+	for _, setting := range userSettings {
+		names = append(names, fmt.Sprintf("'%s'", setting.Name))
+	}
+
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+// defaultUserSettings returns the settings of a newly connected user.
+func defaultUserSettings() *UserSettings {
+	settings := &UserSettings{}
+	for _, setting := range userSettings {
+		setting.set(settings, setting.Default)
+	}
+
+	return settings
+}
+
+// Validate returns an error if the value is not allowed for the setting.
+func (s *userSetting) Validate(value string) error {
+	for _, allowed := range s.AllowedValues {
+		if value == allowed {
+			return nil
+		}
+	}
+
+	quoted := make([]string, len(s.AllowedValues))
+	for i, allowed := range s.AllowedValues {
+		quoted[i] = fmt.Sprintf("'%s'", allowed)
+	}
+
+	return fmt.Errorf("%s is not a valid '%s' setting, must be %s", value, s.Name, strings.Join(quoted, " or "))
+}
+
+// makeSettingsDialog returns a dialog for editing all of a user's settings at
+// once.
+func makeSettingsDialog(settings *UserSettings) model.Dialog {
+	dialog := model.Dialog{
+		CallbackId:  settingsDialogCallbackID,
+		Title:       "Steam Settings",
+		SubmitLabel: "Save",
+	}
+
+	for _, setting := range userSettings {
+		element := model.DialogElement{
+			DisplayName: setting.Name,
+			Name:        setting.Name,
+			Type:        "select",
+			Default:     setting.get(settings),
+			HelpText:    setting.Description,
+		}
+		for _, value := range setting.AllowedValues {
+			element.Options = append(element.Options, &model.PostActionOptions{Text: value, Value: value})
+		}
+		dialog.Elements = append(dialog.Elements, element)
+	}
+
+	return dialog
+}
+
+// applySettingsSubmission updates settings from a submitted settings dialog.
+// The new values of changed settings and any errors are returned per setting
+// name.
+func applySettingsSubmission(settings *UserSettings, submission map[string]interface{}) (map[string]string, map[string]string) {
+	changes := make(map[string]string)
+	fieldErrors := make(map[string]string)
+	for _, setting := range userSettings {
+		value, ok := submission[setting.Name].(string)
+		if !ok {
+			continue
+		}
+		if err := setting.Validate(value); err != nil {
+			fieldErrors[setting.Name] = err.Error()
+			continue
+		}
+		if setting.get(settings) != value {
+			changes[setting.Name] = value
+		}
+		setting.set(settings, value)
+	}
+
+	return changes, fieldErrors
+}
+
+// openSettingsDialog opens the settings dialog for the user who ran a command.
+func (p *Plugin) openSettingsDialog(settings *UserSettings, extra *model.CommandArgs) error {
+	appErr := p.API.OpenInteractiveDialog(model.OpenDialogRequest{
+		TriggerId: extra.TriggerId,
+		URL:       p.getPluginURL(settingsDialogPath),
+		Dialog:    makeSettingsDialog(settings),
+	})
+	if appErr != nil {
+		return errors.Wrap(appErr, "unable to open settings dialog")
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetUserSetting(t *testing.T) {
+	setting := getUserSetting("show-profile")
+	require.NotNil(t, setting)
+	assert.Equal(t, "show-profile", setting.Name)
+
+	setting = getUserSetting("hide-from-leaderboards")
+	require.NotNil(t, setting)
+	assert.Equal(t, "hide-from-stats", setting.Name)
+
+	assert.Nil(t, getUserSetting("true"))
+}
+
+func TestUserSettingValidate(t *testing.T) {
+	setting := getUserSetting("notify-lfg")
+	require.NotNil(t, setting)
+
+	assert.NoError(t, setting.Validate("true"))
+	assert.NoError(t, setting.Validate("false"))
+	assert.EqualError(t, setting.Validate("yes"), "yes is not a valid 'notify-lfg' setting, must be 'true' or 'false'")
+}
+
+func TestApplySettingsSubmission(t *testing.T) {
+	settings := defaultUserSettings()
+
+	changes, fieldErrors := applySettingsSubmission(settings, map[string]interface{}{
+		"show-profile":    "true",
+		"show-activity":   "false",
+		"hide-from-stats": "true",
+		"notify-lfg":      "maybe",
+	})
+	assert.Equal(t, map[string]string{"show-profile": "true", "hide-from-stats": "true"}, changes)
+	assert.Len(t, fieldErrors, 1)
+	assert.Contains(t, fieldErrors, "notify-lfg")
+	assert.True(t, settings.ShowProfile)
+	assert.True(t, settings.HideFromStats)
+	assert.False(t, settings.NotifyLFG)
+}
+
+func TestMakeSettingsDialog(t *testing.T) {
+	settings := defaultUserSettings()
+	settings.AnnouncePresence = true
+
+	dialog := makeSettingsDialog(settings)
+	require.Len(t, dialog.Elements, len(userSettings))
+	for _, element := range dialog.Elements {
+		if element.Name == "announce-presence" {
+			assert.Equal(t, "true", element.Default)
+		} else {
+			assert.Equal(t, "false", element.Default)
+		}
+		assert.Len(t, element.Options, 2)
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// PlayerAchievementsResponse is an API response for a player's achievements
+// in a game.
+type PlayerAchievementsResponse struct {
+	PlayerStats PlayerStats `json:"playerstats"`
+}
+
+// PlayerStats is a player's achievement progress in a game.
+type PlayerStats struct {
+	SteamID      string              `json:"steamID"`
+	GameName     string              `json:"gameName"`
+	Achievements []PlayerAchievement `json:"achievements"`
+	Success      bool                `json:"success"`
+	Error        string              `json:"error"`
+}
+
+// PlayerAchievement is a player's progress on a single achievement.
+type PlayerAchievement struct {
+	APIName    string `json:"apiname"`
+	Achieved   int    `json:"achieved"`
+	UnlockTime int64  `json:"unlocktime"`
+}
+
+// GameSchemaResponse is an API response for a game's stats schema.
+type GameSchemaResponse struct {
+	Game GameSchema `json:"game"`
+}
+
+// GameSchema is the stats schema for a game.
+type GameSchema struct {
+	GameName           string                `json:"gameName"`
+	AvailableGameStats GameSchemaStatsSchema `json:"availableGameStats"`
+}
+
+// GameSchemaStatsSchema is the available stats for a game.
+type GameSchemaStatsSchema struct {
+	Achievements []AchievementSchema `json:"achievements"`
+}
+
+// AchievementSchema is the definition of an achievement.
+type AchievementSchema struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"`
+	IconGray    string `json:"icongray"`
+	Hidden      int    `json:"hidden"`
+}
+
+// GlobalAchievementPercentagesResponse is an API response for the global
+// unlock percentages of a game's achievements.
+type GlobalAchievementPercentagesResponse struct {
+	AchievementPercentages struct {
+		Achievements []GlobalAchievementPercentage `json:"achievements"`
+	} `json:"achievementpercentages"`
+}
+
+// GlobalAchievementPercentage is the percentage of players that have unlocked
+// an achievement.
+type GlobalAchievementPercentage struct {
+	Name    string      `json:"name"`
+	Percent json.Number `json:"percent"`
+}
+
+// Achievement is an achievement combined with a player's progress and global
+// rarity.
+type Achievement struct {
+	APIName       string
+	Name          string
+	Description   string
+	Icon          string
+	Achieved      bool
+	UnlockTime    time.Time
+	GlobalPercent float64
+}
+
+// AchievementProgress is a player's achievements in a game.
+type AchievementProgress struct {
+	AppID        int64
+	GameName     string
+	Achievements []Achievement
+}
+
+// Unlocked returns the number of unlocked achievements.
+func (a *AchievementProgress) Unlocked() int {
+	var unlocked int
+	for _, achievement := range a.Achievements {
+		if achievement.Achieved {
+			unlocked++
+		}
+	}
+
+	return unlocked
+}
+
+// steamNoStatsError is the error Steam reports for games without stats.
+const steamNoStatsError = "Requested app has no stats"
+
+// errNoAchievements is returned when a game has no achievements.
+var errNoAchievements = errors.New("game has no achievements")
+
+// Summary returns the unlocked and total achievement counts in string form.
+func (a *AchievementProgress) Summary() string {
+	if len(a.Achievements) == 0 {
+		return "-"
+	}
+
+	return fmt.Sprintf("%d/%d", a.Unlocked(), len(a.Achievements))
+}
+
+// Summary returns the unlocked and total achievement counts in string form.
+func (s *PlayerStats) Summary() string {
+	if len(s.Achievements) == 0 {
+		return "-"
+	}
+
+	var unlocked int
+	for _, achievement := range s.Achievements {
+		if achievement.Achieved == 1 {
+			unlocked++
+		}
+	}
+
+	return fmt.Sprintf("%d/%d", unlocked, len(s.Achievements))
+}
+
+// getPlayerAchievements returns a user's achievement progress for a game
+// without schema or rarity details, if their privacy settings allow the game
+// to be read for the purpose.
+func (p *Plugin) getPlayerAchievements(userID string, appID int64, access steamDataAccess) (*PlayerStats, error) {
+	userInfo, err := p.getSteamUserForAccess(userID, access)
+	if err != nil {
+		return nil, err
+	}
+	if !userInfo.Settings.AllowsGame(access, appID) {
+		return nil, errSteamDataPrivate
+	}
+
+	result, err := p.makeSteamAPICallForApp(userID+SteamUserKey, steamAPIGetPlayerAchievements, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	var response PlayerAchievementsResponse
+	err = json.Unmarshal(result, &response)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse player achievements")
+	}
+
+	if !response.PlayerStats.Success {
+		// Games without achievements report an error rather than an empty
+		// list.
+		if response.PlayerStats.Error == steamNoStatsError {
+			return nil, errNoAchievements
+		}
+		return nil, fmt.Errorf("unable to get player achievements: %s", response.PlayerStats.Error)
+	}
+
+	return &response.PlayerStats, nil
+}
+
+// getAchievementProgress returns a user's achievements for a game with their
+// names, descriptions and global rarity.
+func (p *Plugin) getAchievementProgress(userID string, appID int64, access steamDataAccess) (*AchievementProgress, error) {
+	stats, err := p.getPlayerAchievements(userID, appID, access)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := p.makeSteamAPICallForApp(userID+SteamUserKey, steamAPIGetSchemaForGame, appID)
+	if err != nil {
+		return nil, err
+	}
+	var schemaResponse GameSchemaResponse
+	err = json.Unmarshal(result, &schemaResponse)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse game schema")
+	}
+
+	percentages, err := getGlobalAchievementPercentages(appID)
+	if err != nil {
+		return nil, err
+	}
+
+	return makeAchievementProgress(appID, stats, &schemaResponse.Game, percentages), nil
+}
+
+// getGlobalAchievementPercentages returns the global unlock percentage of
+// each of a game's achievements keyed by API name.
+func getGlobalAchievementPercentages(appID int64) (map[string]float64, error) {
+	url := fmt.Sprintf("https://api.steampowered.com/%s/?gameid=%d&format=json", steamAPIGetGlobalAchievementPercentages, appID)
+	result, err := steamAPICall(url)
+	if err != nil {
+		return nil, err
+	}
+
+	var response GlobalAchievementPercentagesResponse
+	err = json.Unmarshal(result, &response)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse global achievement percentages")
+	}
+
+	percentages := make(map[string]float64)
+	for _, achievement := range response.AchievementPercentages.Achievements {
+		percent, err := achievement.Percent.Float64()
+		if err != nil {
+			continue
+		}
+		percentages[achievement.Name] = percent
+	}
+
+	return percentages, nil
+}
+
+// makeAchievementProgress combines a player's stats with the game's schema
+// and global rarity. Achievements are sorted with the most recent unlocks
+// first, followed by locked achievements from most to least common.
+func makeAchievementProgress(appID int64, stats *PlayerStats, schema *GameSchema, percentages map[string]float64) *AchievementProgress {
+	progress := &AchievementProgress{
+		AppID:    appID,
+		GameName: schema.GameName,
+	}
+	if stats.GameName != "" {
+		progress.GameName = stats.GameName
+	}
+
+	playerAchievements := make(map[string]PlayerAchievement)
+	for _, achievement := range stats.Achievements {
+		playerAchievements[achievement.APIName] = achievement
+	}
+
+	for _, achievementSchema := range schema.AvailableGameStats.Achievements {
+		playerAchievement := playerAchievements[achievementSchema.Name]
+		achievement := Achievement{
+			APIName:       achievementSchema.Name,
+			Name:          achievementSchema.DisplayName,
+			Description:   achievementSchema.Description,
+			Icon:          achievementSchema.Icon,
+			Achieved:      playerAchievement.Achieved == 1,
+			GlobalPercent: percentages[achievementSchema.Name],
+		}
+		if achievement.Achieved && playerAchievement.UnlockTime > 0 {
+			achievement.UnlockTime = time.Unix(playerAchievement.UnlockTime, 0).UTC()
+		}
+		progress.Achievements = append(progress.Achievements, achievement)
+	}
+
+	sort.SliceStable(progress.Achievements, func(i, j int) bool {
+		a, b := progress.Achievements[i], progress.Achievements[j]
+		if a.Achieved != b.Achieved {
+			return a.Achieved
+		}
+		if a.Achieved {
+			return a.UnlockTime.After(b.UnlockTime)
+		}
+		return a.GlobalPercent > b.GlobalPercent
+	})
+
+	return progress
+}
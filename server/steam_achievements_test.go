@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeAchievementProgress(t *testing.T) {
+	stats := &PlayerStats{
+		GameName: "Portal",
+		Success:  true,
+		Achievements: []PlayerAchievement{
+			{APIName: "old", Achieved: 1, UnlockTime: 1000},
+			{APIName: "new", Achieved: 1, UnlockTime: 2000},
+			{APIName: "common", Achieved: 0},
+		},
+	}
+	schema := &GameSchema{
+		GameName: "Schema Name",
+		AvailableGameStats: GameSchemaStatsSchema{
+			Achievements: []AchievementSchema{
+				{Name: "rare", DisplayName: "Rare"},
+				{Name: "old", DisplayName: "Old"},
+				{Name: "common", DisplayName: "Common"},
+				{Name: "new", DisplayName: "New", Description: "Unlocked last"},
+			},
+		},
+	}
+	percentages := map[string]float64{"rare": 1.5, "old": 50, "common": 80, "new": 10}
+
+	progress := makeAchievementProgress(400, stats, schema, percentages)
+	assert.Equal(t, int64(400), progress.AppID)
+	assert.Equal(t, "Portal", progress.GameName)
+	require.Len(t, progress.Achievements, 4)
+
+	var names []string
+	for _, achievement := range progress.Achievements {
+		names = append(names, achievement.APIName)
+	}
+	assert.Equal(t, []string{"new", "old", "common", "rare"}, names)
+
+	assert.Equal(t, "New", progress.Achievements[0].Name)
+	assert.Equal(t, "Unlocked last", progress.Achievements[0].Description)
+	assert.True(t, progress.Achievements[0].Achieved)
+	assert.Equal(t, time.Unix(2000, 0).UTC(), progress.Achievements[0].UnlockTime)
+	assert.Equal(t, 10.0, progress.Achievements[0].GlobalPercent)
+	assert.False(t, progress.Achievements[3].Achieved)
+	assert.True(t, progress.Achievements[3].UnlockTime.IsZero())
+
+	assert.Equal(t, 2, progress.Unlocked())
+	assert.Equal(t, "2/4", progress.Summary())
+
+	t.Run("schema name fallback", func(t *testing.T) {
+		progress := makeAchievementProgress(400, &PlayerStats{Success: true}, schema, nil)
+		assert.Equal(t, "Schema Name", progress.GameName)
+		assert.Equal(t, 0, progress.Unlocked())
+	})
+}
+
+func TestPlayerStatsSummary(t *testing.T) {
+	stats := &PlayerStats{Achievements: []PlayerAchievement{{Achieved: 1}, {Achieved: 0}, {Achieved: 1}}}
+	assert.Equal(t, "2/3", stats.Summary())
+	assert.Equal(t, "-", (&PlayerStats{}).Summary())
+}
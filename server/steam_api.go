@@ -1,11 +1,19 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
 )
 
+// playerSummariesMaxIDs is the maximum number of Steam IDs that can be
+// requested in a single GetPlayerSummaries call.
+const playerSummariesMaxIDs = 100
+
 func (p *Plugin) makeSteamAPICall(userKey, endpoint string) ([]byte, error) {
 	userInfo, err := p.getSteamUserInfoByKey(userKey)
 	if err != nil {
@@ -14,7 +22,18 @@ func (p *Plugin) makeSteamAPICall(userKey, endpoint string) ([]byte, error) {
 
 	url := fmt.Sprintf("https://api.steampowered.com/%s/?key=%s&steamid=%s&include_appinfo=true&include_played_free_games=true&format=json", endpoint, userInfo.APIToken, userInfo.SteamID)
 
-	return steamAPICall(url)
+	return p.makeUserSteamAPICall(userInfo, url)
+}
+
+func (p *Plugin) makeSteamAPICallForApp(userKey, endpoint string, appID int64) ([]byte, error) {
+	userInfo, err := p.getSteamUserInfoByKey(userKey)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("https://api.steampowered.com/%s/?key=%s&steamid=%s&appid=%d&l=english&format=json", endpoint, userInfo.APIToken, userInfo.SteamID, appID)
+
+	return p.makeUserSteamAPICall(userInfo, url)
 }
 
 func (p *Plugin) makeSteamAPICallSteamIDs(userKey, endpoint string) ([]byte, error) {
@@ -25,20 +44,134 @@ func (p *Plugin) makeSteamAPICallSteamIDs(userKey, endpoint string) ([]byte, err
 
 	url := fmt.Sprintf("https://api.steampowered.com/%s/?key=%s&steamids=%s&format=json", endpoint, userInfo.APIToken, userInfo.SteamID)
 
-	return steamAPICall(url)
+	return p.makeUserSteamAPICall(userInfo, url)
+}
+
+// makeUserSteamAPICall makes a Steam API call with a user's credentials and
+// records when it succeeds.
+func (p *Plugin) makeUserSteamAPICall(userInfo *SteamUserInfo, url string) ([]byte, error) {
+	result, status, err := steamAPICallWithStatus(url)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusOK {
+		p.storeLastSteamCall(userInfo.MattermostUserID)
+	}
+
+	return result, nil
+}
+
+// getPlayerSummaries returns the player summaries for up to
+// playerSummariesMaxIDs Steam IDs in a single call.
+func getPlayerSummaries(apiKey string, steamIDs []string) ([]Player, error) {
+	if len(steamIDs) > playerSummariesMaxIDs {
+		return nil, fmt.Errorf("player summaries are limited to %d Steam IDs per call", playerSummariesMaxIDs)
+	}
+
+	url := fmt.Sprintf("https://api.steampowered.com/%s/?key=%s&steamids=%s&format=json", steamAPIGetPlayerSummaries, apiKey, strings.Join(steamIDs, ","))
+	result, err := steamAPICall(url)
+	if err != nil {
+		return nil, err
+	}
+
+	var playerListResponse PlayersListResponse
+	err = json.Unmarshal(result, &playerListResponse)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse player summaries")
+	}
+
+	return playerListResponse.Response.Players, nil
 }
 
 func steamAPICall(url string) ([]byte, error) {
+	body, _, err := steamAPICallWithStatus(url)
+	return body, err
+}
+
+// steamAPICallWithStatus makes a Steam API call and also returns the HTTP
+// status code of the response.
+func steamAPICallWithStatus(url string) ([]byte, int, error) {
 	resp, err := http.Get(url)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer resp.Body.Close()
 
 	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return body, resp.StatusCode, nil
+}
+
+// getOwnedGamesForUser returns a user's owned games that their privacy
+// settings allow to be read for the purpose.
+func (p *Plugin) getOwnedGamesForUser(userID string, access steamDataAccess) (map[int64]Game, error) {
+	userInfo, err := p.getSteamUserForAccess(userID, access)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := p.makeSteamAPICall(userID+SteamUserKey, steamAPIGetOwnedGames)
 	if err != nil {
 		return nil, err
 	}
 
-	return body, nil
+	gameMap, err := MakeGameMapFromRawGameListResponse(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return userInfo.Settings.filterGameMap(access, gameMap), nil
+}
+
+// getOwnedGamesForUsers returns the owned games of each of the provided users
+// keyed by Mattermost user ID. Users whose privacy settings don't allow their
+// library to be read for the purpose are skipped, and users whose library
+// can't be loaded are logged and skipped.
+func (p *Plugin) getOwnedGamesForUsers(userIDs []string, access steamDataAccess) map[string]map[int64]Game {
+	libraries := make(map[string]map[int64]Game)
+	for _, userID := range userIDs {
+		gameMap, err := p.getOwnedGamesForUser(userID, access)
+		if err == errSteamDataPrivate {
+			continue
+		}
+		if err != nil {
+			p.API.LogError(errors.Wrapf(err, "unable to get owned games for %s", userID).Error())
+			continue
+		}
+		libraries[userID] = gameMap
+	}
+
+	return libraries
+}
+
+// getRecentlyPlayedGames returns the games a user played in the last two
+// weeks that their privacy settings allow to be read for the purpose.
+func (p *Plugin) getRecentlyPlayedGames(userID string, access steamDataAccess) ([]Game, error) {
+	userInfo, err := p.getSteamUserForAccess(userID, access)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := p.makeSteamAPICall(userID+SteamUserKey, steamAPIRecentlyPlayedGames)
+	if err != nil {
+		return nil, err
+	}
+
+	var gameListResponse GamesListResponse
+	err = json.Unmarshal(result, &gameListResponse)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse recently-played games")
+	}
+
+	var games []Game
+	for _, game := range gameListResponse.Response.Games {
+		if userInfo.Settings.AllowsGame(access, game.AppID) {
+			games = append(games, game)
+		}
+	}
+
+	return games, nil
 }
@@ -3,7 +3,22 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	neturl "net/url"
+	"strconv"
 	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Steam storefront category IDs used to identify multiplayer games.
+const (
+	gameCategoryMultiPlayer        = 1
+	gameCategoryCoop               = 9
+	gameCategoryCrossPlatformMulti = 27
+	gameCategoryOnlinePvP          = 36
+	gameCategoryLANPvP             = 37
+	gameCategoryOnlineCoop         = 38
+	gameCategoryLANCoop            = 48
 )
 
 //GamesListResponse is an API response for a list of Steam games.
@@ -46,6 +61,17 @@ type GameStoreData struct {
 	Metacritic  GameMetacritic   `json:"metacritic"`
 	Categories  []GameCategories `json:"categories"`
 	Genres      []GameGenres     `json:"genres"`
+	Price       *GamePrice       `json:"price_overview"`
+}
+
+// GamePrice is the storefront price of a game.
+type GamePrice struct {
+	Currency         string `json:"currency"`
+	Initial          int64  `json:"initial"`
+	Final            int64  `json:"final"`
+	DiscountPercent  int    `json:"discount_percent"`
+	InitialFormatted string `json:"initial_formatted"`
+	FinalFormatted   string `json:"final_formatted"`
 }
 
 // GameMetacritic is Metacritic information for a game.
@@ -91,6 +117,48 @@ func (g *Game) PopulateStoreData() error {
 	return nil
 }
 
+// GetStorePrices returns the storefront prices of many games at once for a
+// country code. Free games and games that aren't sold in the country have no
+// price and are omitted.
+func GetStorePrices(appIDs []int64, countryCode string) (map[int64]*GamePrice, error) {
+	var ids []string
+	for _, appID := range appIDs {
+		ids = append(ids, strconv.FormatInt(appID, 10))
+	}
+
+	url := fmt.Sprintf("https://store.steampowered.com/api/appdetails?appids=%s&filters=price_overview&cc=%s", strings.Join(ids, ","), neturl.QueryEscape(countryCode))
+	result, err := steamAPICall(url)
+	if err != nil {
+		return nil, err
+	}
+
+	var root map[string]struct {
+		Success bool            `json:"success"`
+		Data    json.RawMessage `json:"data"`
+	}
+	err = json.Unmarshal(result, &root)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse store prices")
+	}
+
+	prices := make(map[int64]*GamePrice)
+	for id, response := range root {
+		appID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil || !response.Success {
+			continue
+		}
+
+		// Games without a price return an empty list rather than an object.
+		var data GameStoreData
+		if json.Unmarshal(response.Data, &data) != nil || data.Price == nil {
+			continue
+		}
+		prices[appID] = data.Price
+	}
+
+	return prices, nil
+}
+
 // CategoriesToString returns game category information in string form.
 func (d *GameStoreData) CategoriesToString() string {
 	var categories []string
@@ -101,6 +169,35 @@ func (d *GameStoreData) CategoriesToString() string {
 	return strings.Join(categories, ", ")
 }
 
+// IsMultiplayer returns true if the game has any multiplayer or co-op
+// storefront category.
+func (d *GameStoreData) IsMultiplayer() bool {
+	for _, category := range d.Categories {
+		switch category.ID {
+		case gameCategoryMultiPlayer, gameCategoryCoop, gameCategoryCrossPlatformMulti,
+			gameCategoryOnlinePvP, gameCategoryOnlineCoop, gameCategoryLANPvP, gameCategoryLANCoop:
+			return true
+		}
+	}
+
+	return false
+}
+
+// PriceToString returns game price information in string form.
+func (d *GameStoreData) PriceToString() string {
+	if d.IsFree {
+		return "Free"
+	}
+	if d.Price == nil {
+		return "Paid"
+	}
+	if d.Price.DiscountPercent > 0 {
+		return fmt.Sprintf("%s (-%d%%, was %s)", d.Price.FinalFormatted, d.Price.DiscountPercent, d.Price.InitialFormatted)
+	}
+
+	return d.Price.FinalFormatted
+}
+
 // GenresToString returns game genre information in string form.
 func (d *GameStoreData) GenresToString() string {
 	var genres []string
@@ -126,3 +223,96 @@ func MakeGameMapFromRawGameListResponse(rawGameListResponse []byte) (map[int64]G
 
 	return gameMap, nil
 }
+
+// StoreSearchResponse is the storefront search response.
+type StoreSearchResponse struct {
+	Total int64             `json:"total"`
+	Items []StoreSearchItem `json:"items"`
+}
+
+// StoreSearchItem is a single storefront search result.
+type StoreSearchItem struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	ID   int64  `json:"id"`
+}
+
+// SearchStoreForGame returns the best storefront match for a game name.
+func SearchStoreForGame(term string) (*Game, error) {
+	url := fmt.Sprintf("https://store.steampowered.com/api/storesearch/?term=%s&l=english&cc=US", neturl.QueryEscape(term))
+	result, err := steamAPICall(url)
+	if err != nil {
+		return nil, err
+	}
+
+	var searchResponse StoreSearchResponse
+	err = json.Unmarshal(result, &searchResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range searchResponse.Items {
+		if item.Type != "app" {
+			continue
+		}
+
+		return &Game{AppID: item.ID, Name: item.Name}, nil
+	}
+
+	return nil, fmt.Errorf("no game found matching %s", term)
+}
+
+// FindGameInLibraries looks for a game by app ID or name in the provided game
+// maps. Exact name matches are preferred over partial matches, and the
+// shortest partial match wins. Returns nil if no game matches.
+func FindGameInLibraries(query string, libraries ...map[int64]Game) *Game {
+	query = strings.TrimSpace(query)
+	appID, err := strconv.ParseInt(query, 10, 64)
+	if err == nil {
+		for _, gameMap := range libraries {
+			if game, ok := gameMap[appID]; ok {
+				return &game
+			}
+		}
+
+		return &Game{AppID: appID}
+	}
+
+	lowerQuery := strings.ToLower(query)
+
+	var partial *Game
+	for _, gameMap := range libraries {
+		for _, game := range gameMap {
+			name := strings.ToLower(game.Name)
+			if name == lowerQuery {
+				match := game
+				return &match
+			}
+			if !strings.Contains(name, lowerQuery) {
+				continue
+			}
+			if partial == nil || len(game.Name) < len(partial.Name) ||
+				(len(game.Name) == len(partial.Name) && game.Name < partial.Name) {
+				match := game
+				partial = &match
+			}
+		}
+	}
+
+	return partial
+}
+
+// ResolveGame resolves a game name or app ID, preferring games found in the
+// provided libraries and falling back to a storefront search.
+func ResolveGame(query string, libraries ...map[int64]Game) (*Game, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, errors.New("must provide a game name or app ID")
+	}
+
+	game := FindGameInLibraries(query, libraries...)
+	if game != nil {
+		return game, nil
+	}
+
+	return SearchStoreForGame(query)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindGameInLibraries(t *testing.T) {
+	library1 := map[int64]Game{
+		548430: {AppID: 548430, Name: "Deep Rock Galactic"},
+		730:    {AppID: 730, Name: "Counter-Strike: Global Offensive"},
+	}
+	library2 := map[int64]Game{
+		1:  {AppID: 1, Name: "Deep Rock Galactic Soundtrack"},
+		10: {AppID: 10, Name: "Counter-Strike"},
+	}
+
+	t.Run("app ID", func(t *testing.T) {
+		game := FindGameInLibraries("730", library1, library2)
+		require.NotNil(t, game)
+		assert.Equal(t, "Counter-Strike: Global Offensive", game.Name)
+	})
+
+	t.Run("unknown app ID", func(t *testing.T) {
+		game := FindGameInLibraries("440", library1, library2)
+		require.NotNil(t, game)
+		assert.Equal(t, int64(440), game.AppID)
+		assert.Empty(t, game.Name)
+	})
+
+	t.Run("exact name", func(t *testing.T) {
+		game := FindGameInLibraries("counter-strike", library1, library2)
+		require.NotNil(t, game)
+		assert.Equal(t, int64(10), game.AppID)
+	})
+
+	t.Run("shortest partial name", func(t *testing.T) {
+		game := FindGameInLibraries("deep rock", library1, library2)
+		require.NotNil(t, game)
+		assert.Equal(t, int64(548430), game.AppID)
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		assert.Nil(t, FindGameInLibraries("portal", library1, library2))
+	})
+}
@@ -8,8 +8,11 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"io"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/mattermost/mattermost-server/model"
 	"github.com/pkg/errors"
 )
 
@@ -18,12 +21,30 @@ const (
 	// that fails on a race.
 	StoreSteamRetries = 3
 
+	// kvListPerPage is the page size used when listing store keys.
+	kvListPerPage = 1000
+
 	// SteamUserKey is the store suffix for a Steam profile.
 	SteamUserKey = "_steam_user"
 
+	// LastSteamCallKey is the store prefix for the time of a user's last
+	// successful Steam API call.
+	LastSteamCallKey = "last_steam_call_"
+
+	// lastSteamCallPrecision is how out of date the stored time of a user's
+	// last successful Steam API call may be.
+	lastSteamCallPrecision = time.Hour
+
 	steamAPIGetOwnedGames       = "IPlayerService/GetOwnedGames/v0001"
 	steamAPIRecentlyPlayedGames = "IPlayerService/GetRecentlyPlayedGames/v0001"
-	steamAPIGetSchemaForGame    = "IPlayerService/GetSchemaForGame/v0001"
+	steamAPIGetSchemaForGame    = "ISteamUserStats/GetSchemaForGame/v2"
+	steamAPIGetPlayerSummaries  = "ISteamUser/GetPlayerSummaries/v0002"
+	steamAPIGetSteamLevel       = "IPlayerService/GetSteamLevel/v1"
+	steamAPIGetFriendList       = "ISteamUser/GetFriendList/v0001"
+	steamAPIGetWishlist         = "IWishlistService/GetWishlist/v1"
+
+	steamAPIGetPlayerAchievements           = "ISteamUserStats/GetPlayerAchievements/v0001"
+	steamAPIGetGlobalAchievementPercentages = "ISteamUserStats/GetGlobalAchievementPercentagesForApp/v0002"
 )
 
 // SteamUserInfo is the Steam profile information stored in the database.
@@ -32,11 +53,26 @@ type SteamUserInfo struct {
 	SteamID          string        `json:"steam_id"`
 	APIToken         string        `json:"api_token"`
 	Settings         *UserSettings `json:"user_settings"`
+
+	// ConnectedAt is when the account was connected in milliseconds. It is
+	// zero for accounts connected before it was recorded.
+	ConnectedAt int64 `json:"connected_at,omitempty"`
 }
 
 // UserSettings are user-specific settings that they can control.
 type UserSettings struct {
-	ShowProfile bool `json:"show_profile"`
+	ShowProfile          bool    `json:"show_profile"`
+	HideLibrary          bool    `json:"hide_library"`
+	HideFromStats        bool    `json:"hide_from_stats"`
+	AnnouncePresence     bool    `json:"announce_presence"`
+	ShowActivity         bool    `json:"show_activity"`
+	AnnounceAchievements bool    `json:"announce_achievements"`
+	NotifyLFG            bool    `json:"notify_lfg"`
+	HiddenGames          []int64 `json:"hidden_games,omitempty"`
+
+	// HideFromLeaderboards is replaced by HideFromStats and only read to
+	// migrate existing settings.
+	HideFromLeaderboards bool `json:"hide_from_leaderboards,omitempty"`
 }
 
 func (p *Plugin) storeSteamUser(info *SteamUserInfo) error {
@@ -83,9 +119,37 @@ func (p *Plugin) getSteamUserInfoByKey(key string) (*SteamUserInfo, error) {
 
 	userInfo.APIToken = unencryptedToken
 
+	legacy := isLegacyUserSettings(infoBytes)
+	if userInfo.Settings == nil {
+		userInfo.Settings = defaultUserSettings()
+	}
+	if legacy {
+		// Users with settings from before hide-library relied on
+		// show-profile to keep their library private.
+		userInfo.Settings.HideLibrary = !userInfo.Settings.ShowProfile
+	}
+	if userInfo.Settings.HideFromLeaderboards {
+		userInfo.Settings.HideFromStats = true
+		userInfo.Settings.HideFromLeaderboards = false
+	}
+
 	return &userInfo, nil
 }
 
+// isLegacyUserSettings returns true if the stored user info has no settings
+// or settings from before hide-library was added.
+func isLegacyUserSettings(infoBytes []byte) bool {
+	var raw struct {
+		Settings map[string]json.RawMessage `json:"user_settings"`
+	}
+	if json.Unmarshal(infoBytes, &raw) != nil {
+		return false
+	}
+
+	_, ok := raw.Settings["hide_library"]
+	return !ok
+}
+
 func (p *Plugin) getSteamUserInfoByID(userID string) (*SteamUserInfo, error) {
 	return p.getSteamUserInfoByKey(userID + SteamUserKey)
 }
@@ -101,9 +165,104 @@ func (p *Plugin) deleteSteamUser(userID string) error {
 		return errors.Wrap(appErr, "unable to delete user info in database")
 	}
 
+	for _, key := range []string{UserInfoCacheKey + userID, UserInfoSummaryCacheKey + userID, LastSteamCallKey + userID, PriceAlertsKey + userID, PresenceStateKey + userID} {
+		appErr = p.API.KVDelete(key)
+		if appErr != nil {
+			p.API.LogError(errors.Wrapf(appErr, "unable to delete %s in database", key).Error())
+		}
+	}
+
+	err = p.deleteKVKeysWithPrefix(playtimeSnapshotKeyPrefix(userID), AchievementStateKey+userID+"_")
+	if err != nil {
+		p.API.LogError(errors.Wrapf(err, "unable to delete stored Steam data for %s", userID).Error())
+	}
+
+	return nil
+}
+
+// deleteKVKeysWithPrefix deletes every key that starts with one of the
+// prefixes.
+func (p *Plugin) deleteKVKeysWithPrefix(prefixes ...string) error {
+	keys, err := p.getAllKVKeys()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		for _, prefix := range prefixes {
+			if !strings.HasPrefix(key, prefix) {
+				continue
+			}
+
+			appErr := p.API.KVDelete(key)
+			if appErr != nil {
+				return errors.Wrapf(appErr, "unable to delete %s in database", key)
+			}
+			break
+		}
+	}
+
 	return nil
 }
 
+// storeLastSteamCall records that a Steam API call for the user succeeded.
+// The time is only written once lastSteamCallPrecision has passed since the
+// stored time, so that every call doesn't write to the database.
+func (p *Plugin) storeLastSteamCall(userID string) {
+	lastCall, err := p.getLastSteamCall(userID)
+	if err == nil && time.Since(lastCall) < lastSteamCallPrecision {
+		return
+	}
+
+	appErr := p.API.KVSet(LastSteamCallKey+userID, []byte(strconv.FormatInt(model.GetMillis(), 10)))
+	if appErr != nil {
+		p.API.LogError(errors.Wrapf(appErr, "unable to store last steam call for %s in database", userID).Error())
+	}
+}
+
+// getLastSteamCall returns the time of the user's last successful Steam API
+// call, or the zero time if none was recorded.
+func (p *Plugin) getLastSteamCall(userID string) (time.Time, error) {
+	value, appErr := p.API.KVGet(LastSteamCallKey + userID)
+	if appErr != nil {
+		return time.Time{}, errors.Wrap(appErr, "unable to get last steam call in database")
+	}
+	if value == nil {
+		return time.Time{}, nil
+	}
+
+	millis, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "unable to parse last steam call")
+	}
+
+	return time.Unix(0, millis*int64(time.Millisecond)), nil
+}
+
+// purgeSteamCaches deletes all cached Steam data. It is rebuilt from the
+// Steam API as needed.
+func (p *Plugin) purgeSteamCaches() (int, error) {
+	keys, err := p.getAllKVKeys()
+	if err != nil {
+		return 0, err
+	}
+
+	var purged int
+	for _, key := range keys {
+		if !strings.HasPrefix(key, UserInfoCacheKey) && !strings.HasPrefix(key, UserInfoSummaryCacheKey) {
+			continue
+		}
+
+		appErr := p.API.KVDelete(key)
+		if appErr != nil {
+			return purged, errors.Wrap(appErr, "unable to delete cached data in database")
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
 func removeNonPlayerKVKeys(keys []string) []string {
 	var cleanedKeys []string
 
@@ -118,8 +277,60 @@ func removeNonPlayerKVKeys(keys []string) []string {
 	return cleanedKeys
 }
 
+// getAllKVKeys returns every key in the plugin store.
+func (p *Plugin) getAllKVKeys() ([]string, error) {
+	var keys []string
+	for page := 0; ; page++ {
+		pageKeys, appErr := p.API.KVList(page, kvListPerPage)
+		if appErr != nil {
+			return nil, errors.Wrap(appErr, "unable to list keys in database")
+		}
+		keys = append(keys, pageKeys...)
+
+		if len(pageKeys) < kvListPerPage {
+			return keys, nil
+		}
+	}
+}
+
+// getSteamUserIDs returns the Mattermost user IDs of all connected Steam users.
+func (p *Plugin) getSteamUserIDs() ([]string, error) {
+	keys, err := p.getAllKVKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	var userIDs []string
+	for _, key := range removeNonPlayerKVKeys(keys) {
+		userIDs = append(userIDs, strings.TrimSuffix(key, SteamUserKey))
+	}
+
+	return userIDs, nil
+}
+
+// getSteamUsers returns the stored information of all connected Steam users.
+// Users whose information can't be loaded are logged and skipped.
+func (p *Plugin) getSteamUsers() ([]*SteamUserInfo, error) {
+	userIDs, err := p.getSteamUserIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	var userInfos []*SteamUserInfo
+	for _, userID := range userIDs {
+		userInfo, err := p.getSteamUserInfoByID(userID)
+		if err != nil {
+			p.API.LogError(errors.Wrapf(err, "unable to get steam user %s", userID).Error())
+			continue
+		}
+		userInfos = append(userInfos, userInfo)
+	}
+
+	return userInfos, nil
+}
+
 func (p *Plugin) getSteamInfoForUser(userID string) (*Player, error) {
-	result, err := p.makeSteamAPICallSteamIDs(userID+SteamUserKey, "ISteamUser/GetPlayerSummaries/v0002")
+	result, err := p.makeSteamAPICallSteamIDs(userID+SteamUserKey, steamAPIGetPlayerSummaries)
 	if err != nil {
 		return nil, err
 	}
@@ -133,6 +344,10 @@ func (p *Plugin) getSteamInfoForUser(userID string) (*Player, error) {
 		return nil, err
 	}
 
+	if len(playerListResponse.Response.Players) == 0 {
+		return nil, nil
+	}
+
 	return &playerListResponse.Response.Players[0], nil
 }
 
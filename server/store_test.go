@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsLegacyUserSettings(t *testing.T) {
+	assert.True(t, isLegacyUserSettings([]byte(`{"steam_id":"1"}`)))
+	assert.True(t, isLegacyUserSettings([]byte(`{"steam_id":"1","user_settings":null}`)))
+	assert.True(t, isLegacyUserSettings([]byte(`{"steam_id":"1","user_settings":{"show_profile":true}}`)))
+	assert.False(t, isLegacyUserSettings([]byte(`{"steam_id":"1","user_settings":{"show_profile":true,"hide_library":false}}`)))
+	assert.False(t, isLegacyUserSettings([]byte(`not json`)))
+}
+
+func TestGetSteamUserInfoLegacySettings(t *testing.T) {
+	token, err := encrypt([]byte(testEncryptionKey), "token")
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name        string
+		settings    string
+		hideLibrary bool
+	}{
+		{"no settings", `null`, true},
+		{"private profile", `{"show_profile":false}`, true},
+		{"public profile", `{"show_profile":true}`, false},
+		{"current settings", `{"show_profile":false,"hide_library":false}`, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			api := &plugintest.API{}
+			api.On("KVGet", "user1"+SteamUserKey).Return([]byte(fmt.Sprintf(`{"mattermost_user_id":"user1","api_token":%q,"user_settings":%s}`, token, tc.settings)), nil)
+			defer api.AssertExpectations(t)
+
+			userInfo, err := newTestPlugin(api).getSteamUserInfoByID("user1")
+			require.NoError(t, err)
+			assert.Equal(t, tc.hideLibrary, userInfo.Settings.HideLibrary)
+			assert.Equal(t, "token", userInfo.APIToken)
+		})
+	}
+}
+
+func TestStoreLastSteamCall(t *testing.T) {
+	t.Run("recent call is not written again", func(t *testing.T) {
+		recent := strconv.FormatInt(model.GetMillis()-int64(time.Minute/time.Millisecond), 10)
+
+		api := &plugintest.API{}
+		api.On("KVGet", LastSteamCallKey+"user1").Return([]byte(recent), nil)
+		defer api.AssertExpectations(t)
+
+		newTestPlugin(api).storeLastSteamCall("user1")
+		api.AssertNotCalled(t, "KVSet", mock.Anything, mock.Anything)
+	})
+
+	t.Run("old call is updated", func(t *testing.T) {
+		old := strconv.FormatInt(model.GetMillis()-int64(2*time.Hour/time.Millisecond), 10)
+
+		api := &plugintest.API{}
+		api.On("KVGet", LastSteamCallKey+"user1").Return([]byte(old), nil)
+		api.On("KVSet", LastSteamCallKey+"user1", mock.Anything).Return(nil)
+		defer api.AssertExpectations(t)
+
+		newTestPlugin(api).storeLastSteamCall("user1")
+	})
+
+	t.Run("first call is written", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("KVGet", LastSteamCallKey+"user1").Return(nil, nil)
+		api.On("KVSet", LastSteamCallKey+"user1", mock.Anything).Return(nil)
+		defer api.AssertExpectations(t)
+
+		newTestPlugin(api).storeLastSteamCall("user1")
+	})
+}
+
+func TestDeleteSteamUser(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("KVGet", "user1"+SteamUserKey).Return(makeTestSteamUserBytes(t, "user1", defaultUserSettings()), nil)
+	api.On("KVList", 0, kvListPerPage).Return([]string{
+		"user1" + SteamUserKey,
+		playtimeSnapshotKey("user1", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)),
+		playtimeSnapshotKey("user2", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)),
+		achievementStateKey("user1", 10),
+		achievementStateKey("user10", 10),
+	}, nil)
+	for _, key := range []string{
+		"user1" + SteamUserKey,
+		UserInfoCacheKey + "user1",
+		UserInfoSummaryCacheKey + "user1",
+		LastSteamCallKey + "user1",
+		PriceAlertsKey + "user1",
+		PresenceStateKey + "user1",
+		playtimeSnapshotKey("user1", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)),
+		achievementStateKey("user1", 10),
+	} {
+		api.On("KVDelete", key).Return(nil).Once()
+	}
+	defer api.AssertExpectations(t)
+
+	require.NoError(t, newTestPlugin(api).deleteSteamUser("user1"))
+	api.AssertNumberOfCalls(t, "KVDelete", 8)
+}
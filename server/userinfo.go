@@ -0,0 +1,291 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// UserInfoCacheKey is the store prefix for cached profile information.
+	UserInfoCacheKey = "userinfo_cache_"
+
+	// UserInfoSummaryCacheKey is the store prefix for cached profile
+	// information built from a player summary alone, without the Steam level
+	// and recently played games. It is only served to batch requests.
+	UserInfoSummaryCacheKey = "userinfo_summary_"
+
+	// userInfoCacheSeconds is how long profile information is cached for.
+	userInfoCacheSeconds = 60
+
+	// userInfoRecentGames is the number of recently played games returned.
+	userInfoRecentGames = 3
+
+	// userInfoBatchMaxUsers is the maximum number of users that can be
+	// requested in a batch.
+	userInfoBatchMaxUsers = 200
+
+	userInfoStatusOK           = "ok"
+	userInfoStatusNotConnected = "not_connected"
+	userInfoStatusPrivate      = "private"
+	userInfoStatusUnavailable  = "unavailable"
+)
+
+// personaStates are the descriptions of Steam persona states.
+var personaStates = []string{"Offline", "Online", "Busy", "Away", "Snooze", "Looking to trade", "Looking to play"}
+
+// SteamUserInfoResponse is the Steam information shown in a user's profile.
+type SteamUserInfoResponse struct {
+	SteamID     string              `json:"steamid"`
+	PersonaName string              `json:"personaname"`
+	ProfileURL  string              `json:"profileurl"`
+	Avatar      string              `json:"avatar"`
+	SteamLevel  int                 `json:"steam_level"`
+	OnlineState string              `json:"online_state,omitempty"`
+	CurrentGame *SteamUserInfoGame  `json:"current_game,omitempty"`
+	RecentGames []SteamUserInfoGame `json:"recent_games,omitempty"`
+}
+
+// SteamUserInfoGame is a game shown in a user's profile.
+type SteamUserInfoGame struct {
+	AppID           int64  `json:"appid"`
+	Name            string `json:"name"`
+	StoreLink       string `json:"store_link"`
+	IconURL         string `json:"icon_url"`
+	TwoWeekPlaytime int64  `json:"playtime_2weeks,omitempty"`
+}
+
+// SteamUserInfoBatchResult is the result for a single user in a batch
+// request.
+type SteamUserInfoBatchResult struct {
+	Status string                 `json:"status"`
+	Info   *SteamUserInfoResponse `json:"info,omitempty"`
+}
+
+// SteamLevelResponse is an API response for a player's Steam level.
+type SteamLevelResponse struct {
+	Response struct {
+		PlayerLevel int `json:"player_level"`
+	} `json:"response"`
+}
+
+// ApplySettings removes information the user has chosen not to show.
+func (r *SteamUserInfoResponse) ApplySettings(settings *UserSettings) {
+	if !settings.ShowActivity {
+		r.OnlineState = ""
+		r.CurrentGame = nil
+		r.RecentGames = nil
+		return
+	}
+
+	if r.CurrentGame != nil && !settings.AllowsGame(accessProfile, r.CurrentGame.AppID) {
+		r.CurrentGame = nil
+	}
+
+	var recentGames []SteamUserInfoGame
+	for _, game := range r.RecentGames {
+		if settings.AllowsGame(accessProfile, game.AppID) {
+			recentGames = append(recentGames, game)
+		}
+	}
+	r.RecentGames = recentGames
+}
+
+// getSteamUserInfoResponse returns the profile information for a user,
+// served from a short-lived cache when possible. Visibility settings are not
+// applied.
+func (p *Plugin) getSteamUserInfoResponse(userID string) (*SteamUserInfoResponse, error) {
+	response, err := p.getCachedSteamUserInfoResponse(UserInfoCacheKey + userID)
+	if err != nil {
+		return nil, err
+	}
+	if response != nil {
+		return response, nil
+	}
+
+	player, err := p.getSteamInfoForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if player == nil {
+		return nil, errors.New("no player summary found")
+	}
+
+	return p.buildSteamUserInfoResponse(userID, player)
+}
+
+// getCachedSteamUserInfoResponse returns the profile information cached under
+// a key, or nil if there isn't any.
+func (p *Plugin) getCachedSteamUserInfoResponse(key string) (*SteamUserInfoResponse, error) {
+	responseBytes, appErr := p.API.KVGet(key)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "unable to get cached user info")
+	}
+	if responseBytes == nil {
+		return nil, nil
+	}
+
+	var response SteamUserInfoResponse
+	err := json.Unmarshal(responseBytes, &response)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse cached user info")
+	}
+
+	return &response, nil
+}
+
+// getSteamUserInfoBatch returns the profile information for many users.
+// Cached information is used when available, and the rest is resolved with
+// batched player summary calls. As those lack the Steam level and recently
+// played games, they are cached separately from full profile information.
+func (p *Plugin) getSteamUserInfoBatch(userIDs []string) map[string]*SteamUserInfoBatchResult {
+	results := make(map[string]*SteamUserInfoBatchResult)
+	settings := make(map[string]*UserSettings)
+
+	var uncached []*SteamUserInfo
+	for _, userID := range userIDs {
+		if _, ok := results[userID]; ok {
+			continue
+		}
+
+		userInfo, err := p.getSteamUserInfoByID(userID)
+		if err != nil {
+			results[userID] = &SteamUserInfoBatchResult{Status: userInfoStatusNotConnected}
+			continue
+		}
+		if !userInfo.Settings.Allows(accessProfile) {
+			results[userID] = &SteamUserInfoBatchResult{Status: userInfoStatusPrivate}
+			continue
+		}
+		settings[userID] = userInfo.Settings
+
+		response, err := p.getCachedSteamUserInfoResponse(UserInfoCacheKey + userID)
+		if err != nil {
+			p.API.LogError(err.Error())
+		}
+		if response == nil {
+			response, err = p.getCachedSteamUserInfoResponse(UserInfoSummaryCacheKey + userID)
+			if err != nil {
+				p.API.LogError(err.Error())
+			}
+		}
+		if response != nil {
+			response.ApplySettings(userInfo.Settings)
+			results[userID] = &SteamUserInfoBatchResult{Status: userInfoStatusOK, Info: response}
+			continue
+		}
+
+		results[userID] = &SteamUserInfoBatchResult{Status: userInfoStatusUnavailable}
+		uncached = append(uncached, userInfo)
+	}
+
+	for userID, player := range p.getPlayerSummariesForUsers(uncached) {
+		response := makeSteamUserInfoResponseFromPlayer(&player)
+		p.cacheSteamUserInfoResponse(UserInfoSummaryCacheKey+userID, response)
+		response.ApplySettings(settings[userID])
+		results[userID] = &SteamUserInfoBatchResult{Status: userInfoStatusOK, Info: response}
+	}
+
+	return results
+}
+
+// buildSteamUserInfoResponse builds and caches the profile information for a
+// user from their player summary.
+func (p *Plugin) buildSteamUserInfoResponse(userID string, player *Player) (*SteamUserInfoResponse, error) {
+	response := makeSteamUserInfoResponseFromPlayer(player)
+
+	result, err := p.makeSteamAPICall(userID+SteamUserKey, steamAPIGetSteamLevel)
+	if err != nil {
+		p.API.LogError(errors.Wrapf(err, "unable to get steam level for %s", userID).Error())
+	} else {
+		var levelResponse SteamLevelResponse
+		if json.Unmarshal(result, &levelResponse) == nil {
+			response.SteamLevel = levelResponse.Response.PlayerLevel
+		}
+	}
+
+	result, err = p.makeSteamAPICall(userID+SteamUserKey, steamAPIRecentlyPlayedGames)
+	if err != nil {
+		p.API.LogError(errors.Wrapf(err, "unable to get recently-played games for %s", userID).Error())
+	} else {
+		var gameListResponse GamesListResponse
+		if json.Unmarshal(result, &gameListResponse) == nil {
+			for _, game := range gameListResponse.Response.Games {
+				userInfoGame := makeSteamUserInfoGame(&game)
+				if strconv.FormatInt(game.AppID, 10) == player.GameID {
+					response.CurrentGame = &userInfoGame
+				}
+				if len(response.RecentGames) < userInfoRecentGames {
+					response.RecentGames = append(response.RecentGames, userInfoGame)
+				}
+			}
+		}
+	}
+
+	p.cacheSteamUserInfoResponse(UserInfoCacheKey+userID, response)
+
+	return response, nil
+}
+
+// cacheSteamUserInfoResponse caches a user's profile information under a key
+// before visibility settings are applied. Failures are only logged.
+func (p *Plugin) cacheSteamUserInfoResponse(key string, response *SteamUserInfoResponse) {
+	responseBytes, err := json.Marshal(response)
+	if err != nil {
+		p.API.LogError(errors.Wrap(err, "unable to marshal user info").Error())
+		return
+	}
+
+	appErr := p.API.KVSetWithExpiry(key, responseBytes, userInfoCacheSeconds)
+	if appErr != nil {
+		p.API.LogError(errors.Wrap(appErr, "unable to cache user info").Error())
+	}
+}
+
+// makeSteamUserInfoResponseFromPlayer returns the profile information that is
+// available from a player summary alone.
+func makeSteamUserInfoResponseFromPlayer(player *Player) *SteamUserInfoResponse {
+	response := &SteamUserInfoResponse{
+		SteamID:     player.SteamID,
+		PersonaName: player.PersonaName,
+		ProfileURL:  player.ProfileURL,
+		Avatar:      player.Avatar,
+		CurrentGame: makeCurrentSteamUserInfoGame(player),
+	}
+	if player.PersonaState >= 0 && player.PersonaState < len(personaStates) {
+		response.OnlineState = personaStates[player.PersonaState]
+	}
+
+	return response
+}
+
+// makeCurrentSteamUserInfoGame returns the game a player is currently
+// playing, or nil if they aren't in game.
+func makeCurrentSteamUserInfoGame(player *Player) *SteamUserInfoGame {
+	if player.GameID == "" {
+		return nil
+	}
+	appID, err := strconv.ParseInt(player.GameID, 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	game := Game{AppID: appID, Name: player.GameExtraInfo}
+	return &SteamUserInfoGame{
+		AppID:     appID,
+		Name:      game.Name,
+		StoreLink: game.StoreLink(),
+		IconURL:   gameCapsuleImgURL(player.GameID),
+	}
+}
+
+func makeSteamUserInfoGame(game *Game) SteamUserInfoGame {
+	return SteamUserInfoGame{
+		AppID:           game.AppID,
+		Name:            game.Name,
+		StoreLink:       game.StoreLink(),
+		IconURL:         gameImgURL(strconv.FormatInt(game.AppID, 10), game.ImgIconURL),
+		TwoWeekPlaytime: game.TwoWeekPlaytime,
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testEncryptionKey = "0123456789abcdef0123456789abcdef"
+
+// newTestPlugin returns a plugin backed by the mocked API.
+func newTestPlugin(api *plugintest.API) *Plugin {
+	p := &Plugin{}
+	p.setConfiguration(&configuration{EncryptionKey: testEncryptionKey})
+	p.SetAPI(api)
+
+	return p
+}
+
+// makeTestSteamUserBytes returns a stored Steam user with the given settings.
+func makeTestSteamUserBytes(t *testing.T, userID string, settings *UserSettings) []byte {
+	token, err := encrypt([]byte(testEncryptionKey), "token")
+	require.NoError(t, err)
+
+	userInfoBytes, err := json.Marshal(&SteamUserInfo{
+		MattermostUserID: userID,
+		SteamID:          "76561197960287930",
+		APIToken:         token,
+		Settings:         settings,
+	})
+	require.NoError(t, err)
+
+	return userInfoBytes
+}
+
+func makeTestSteamUserInfoResponse() *SteamUserInfoResponse {
+	return &SteamUserInfoResponse{
+		SteamID:     "76561197960287930",
+		PersonaName: "player",
+		OnlineState: "Online",
+		CurrentGame: &SteamUserInfoGame{AppID: 10, Name: "Counter-Strike"},
+		RecentGames: []SteamUserInfoGame{
+			{AppID: 10, Name: "Counter-Strike"},
+			{AppID: 20, Name: "Team Fortress Classic"},
+		},
+	}
+}
+
+func TestSteamUserInfoResponseApplySettings(t *testing.T) {
+	t.Run("activity hidden", func(t *testing.T) {
+		settings := defaultUserSettings()
+		settings.ShowProfile = true
+
+		response := makeTestSteamUserInfoResponse()
+		response.ApplySettings(settings)
+		assert.Equal(t, "player", response.PersonaName)
+		assert.Empty(t, response.OnlineState)
+		assert.Nil(t, response.CurrentGame)
+		assert.Nil(t, response.RecentGames)
+	})
+
+	t.Run("activity shown", func(t *testing.T) {
+		settings := defaultUserSettings()
+		settings.ShowProfile = true
+		settings.ShowActivity = true
+
+		response := makeTestSteamUserInfoResponse()
+		response.ApplySettings(settings)
+		assert.Equal(t, "Online", response.OnlineState)
+		require.NotNil(t, response.CurrentGame)
+		assert.Len(t, response.RecentGames, 2)
+	})
+
+	t.Run("hidden games", func(t *testing.T) {
+		settings := defaultUserSettings()
+		settings.ShowProfile = true
+		settings.ShowActivity = true
+		settings.HiddenGames = []int64{10}
+
+		response := makeTestSteamUserInfoResponse()
+		response.ApplySettings(settings)
+		assert.Equal(t, "Online", response.OnlineState)
+		assert.Nil(t, response.CurrentGame)
+		assert.Equal(t, []SteamUserInfoGame{{AppID: 20, Name: "Team Fortress Classic"}}, response.RecentGames)
+	})
+}
+
+func TestGetSteamUserInfoBatch(t *testing.T) {
+	publicSettings := defaultUserSettings()
+	publicSettings.ShowProfile = true
+
+	cachedBytes, err := json.Marshal(makeTestSteamUserInfoResponse())
+	require.NoError(t, err)
+	summaryBytes, err := json.Marshal(&SteamUserInfoResponse{PersonaName: "summary player"})
+	require.NoError(t, err)
+
+	api := &plugintest.API{}
+	api.On("KVGet", "missing"+SteamUserKey).Return(nil, nil)
+	api.On("KVGet", "private"+SteamUserKey).Return(makeTestSteamUserBytes(t, "private", defaultUserSettings()), nil)
+	api.On("KVGet", "cached"+SteamUserKey).Return(makeTestSteamUserBytes(t, "cached", publicSettings), nil)
+	api.On("KVGet", UserInfoCacheKey+"cached").Return(cachedBytes, nil)
+	api.On("KVGet", "summary"+SteamUserKey).Return(makeTestSteamUserBytes(t, "summary", publicSettings), nil)
+	api.On("KVGet", UserInfoCacheKey+"summary").Return(nil, nil)
+	api.On("KVGet", UserInfoSummaryCacheKey+"summary").Return(summaryBytes, nil)
+	api.On("LogError", mock.Anything).Maybe()
+	defer api.AssertExpectations(t)
+
+	results := newTestPlugin(api).getSteamUserInfoBatch([]string{"missing", "private", "cached", "cached", "summary"})
+	require.Len(t, results, 4)
+	assert.Equal(t, userInfoStatusNotConnected, results["missing"].Status)
+	assert.Equal(t, userInfoStatusPrivate, results["private"].Status)
+	assert.Nil(t, results["private"].Info)
+
+	require.Equal(t, userInfoStatusOK, results["cached"].Status)
+	require.NotNil(t, results["cached"].Info)
+	assert.Equal(t, "player", results["cached"].Info.PersonaName)
+	assert.Empty(t, results["cached"].Info.OnlineState)
+
+	require.Equal(t, userInfoStatusOK, results["summary"].Status)
+	assert.Equal(t, "summary player", results["summary"].Info.PersonaName)
+}
+
+func TestGetSteamUserInfoResponseIgnoresSummaryCache(t *testing.T) {
+	cachedBytes, err := json.Marshal(makeTestSteamUserInfoResponse())
+	require.NoError(t, err)
+
+	api := &plugintest.API{}
+	api.On("KVGet", UserInfoCacheKey+"user1").Return(cachedBytes, nil)
+	defer api.AssertExpectations(t)
+
+	response, err := newTestPlugin(api).getSteamUserInfoResponse("user1")
+	require.NoError(t, err)
+	assert.Equal(t, makeTestSteamUserInfoResponse(), response)
+	api.AssertNotCalled(t, "KVGet", UserInfoSummaryCacheKey+"user1")
+}
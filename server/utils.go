@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 func prettyPrintJSON(in string) string {
@@ -27,10 +28,19 @@ func inlineCode(in string) string {
 	return fmt.Sprintf("`%s`", in)
 }
 
+// tableCell escapes text for use in a markdown table cell.
+func tableCell(in string) string {
+	return strings.Replace(in, "|", "\\|", -1)
+}
+
 func gameImgURL(appid, hash string) string {
 	return fmt.Sprintf("https://media.steampowered.com/steamcommunity/public/images/apps/%s/%s.jpg", appid, hash)
 }
 
+func gameCapsuleImgURL(appid string) string {
+	return fmt.Sprintf("https://steamcdn-a.akamaihd.net/steam/apps/%s/capsule_sm_120.jpg", appid)
+}
+
 // NewBool returns a pointer to a given bool.
 func NewBool(b bool) *bool { return &b }
 
@@ -20,6 +20,45 @@ export default class UserAttribute extends React.PureComponent {
             return null;
         }
 
+        let level;
+        if (profile.steam_level) {
+            level = <span style={style.detail}>{` Level ${profile.steam_level}`}</span>;
+        }
+
+        let state;
+        if (profile.online_state) {
+            state = <div style={style.detail}>{profile.online_state}</div>;
+        }
+
+        let currentGame;
+        if (profile.current_game) {
+            currentGame = (
+                <div>
+                    {'Playing '}
+                    <a
+                        href={profile.current_game.store_link}
+                        target='_blank'
+                        rel='noopener noreferrer'
+                    >
+                        <img
+                            src={profile.current_game.icon_url}
+                            style={style.icon}
+                        />
+                        {profile.current_game.name}
+                    </a>
+                </div>
+            );
+        }
+
+        let recentGames;
+        if (profile.recent_games && profile.recent_games.length) {
+            recentGames = (
+                <div style={style.detail}>
+                    {'Recently played: ' + profile.recent_games.map((game) => game.name).join(', ')}
+                </div>
+            );
+        }
+
         return (
             <div style={style.container}>
                 <a
@@ -29,6 +68,10 @@ export default class UserAttribute extends React.PureComponent {
                 >
                     <i className='fa fa-steam'/>{' ' + profile.personaname}
                 </a>
+                {level}
+                {state}
+                {currentGame}
+                {recentGames}
             </div>
         );
     }
@@ -38,4 +81,12 @@ const style = {
     container: {
         margin: '5px 0',
     },
+    detail: {
+        opacity: 0.7,
+    },
+    icon: {
+        height: '16px',
+        marginRight: '4px',
+        verticalAlign: 'middle',
+    },
 };